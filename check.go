@@ -0,0 +1,252 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"time"
+)
+
+// CheckReport is the result of Check: the differences between the columns
+// of a query and the fields of a type.
+type CheckReport struct {
+	Query string
+	Type  reflect.Type
+	// MissingFields are the columns that have no field in Type. They make
+	// Get and Select fail, unless the DB is created WithUnsafe.
+	MissingFields []string
+	// UnusedFields are the names of the fields of Type that no column is
+	// scanned into. Fields of nested structs are only included if another
+	// field of the same struct is scanned.
+	UnusedFields []string
+	// Mismatches are the columns that may fail to scan into their field.
+	Mismatches []TypeMismatch
+}
+
+// OK reports whether the report has no differences.
+func (r *CheckReport) OK() bool {
+	return len(r.MissingFields) == 0 && len(r.UnusedFields) == 0 && len(r.Mismatches) == 0
+}
+
+// Err returns an error that lists the differences of the report, or nil if
+// there are none. Check MissingFields and Mismatches instead if fields are
+// expected to be unused.
+func (r *CheckReport) Err() error {
+	var errs []error
+	for _, c := range r.MissingFields {
+		errs = append(errs, fmt.Errorf("missing destination name %s in %s", c, r.Type))
+	}
+	for _, f := range r.UnusedFields {
+		errs = append(errs, fmt.Errorf("no column for field %s of %s", f, r.Type))
+	}
+	for _, m := range r.Mismatches {
+		errs = append(errs, errors.New(m.String()))
+	}
+	if len(errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("query %q: %w", r.Query, errors.Join(errs...))
+}
+
+// TypeMismatch is a column whose values may not scan into its field.
+type TypeMismatch struct {
+	Column       string
+	DatabaseType string       // the type name reported by the driver, e.g. VARCHAR
+	ScanType     reflect.Type // the scan type reported by the driver
+	Nullable     bool         // whether the column may be NULL
+	Field        reflect.Type
+	Reason       string
+}
+
+func (m TypeMismatch) String() string {
+	return fmt.Sprintf("column %s (%s) cannot be scanned into %s: %s", m.Column, m.DatabaseType, m.Field, m.Reason)
+}
+
+// Check compares the columns of query with the fields of T, as they are
+// mapped by Get and Select with the Mapper of q, and returns a report of
+// the differences. It is meant to be run in tests or at startup against
+// every query of an application, to find queries that would fail to scan
+// before they run in production.
+//
+// The query is run as SELECT * FROM (query) AS dbx_check WHERE 1=0, so
+// that no rows are read, and the columns are taken from the column types
+// reported by the driver. Types are only compared if the driver reports
+// scan types. Columns that are nullable according to the driver are
+// reported if their field can't hold NULL, i.e. it is not a pointer, a
+// sql.Scanner, a []byte or an interface.
+func Check[T any](ctx context.Context, q Queryer, query string, args ...any) (*CheckReport, error) {
+	db := dbOf(q)
+	// the newline ends a comment at the end of query
+	wrapped := "SELECT * FROM (" + strings.TrimSuffix(strings.TrimSpace(query), ";") + "\n) AS dbx_check WHERE 1=0"
+	bound, boundArgs, err := db.bind(wrapped, args, true)
+	if err != nil {
+		return nil, db.queryErr(err, wrapped, args, nil, -1)
+	}
+	_, inTx := q.(*Tx)
+	ctx, after := db.beforeQuery(ctx, inTx, QuerySelect, bound, boundArgs)
+	var failed error
+	defer func() { after(0, failed) }()
+
+	rows, err := db.queryContext(ctx, q, bound, boundArgs)
+	if err != nil {
+		failed = db.queryErr(err, bound, boundArgs, nil, -1)
+		return nil, failed
+	}
+	defer func() { _ = rows.Close() }()
+	columns, err := rows.ColumnTypes()
+	if err != nil {
+		failed = db.queryErr(err, bound, boundArgs, nil, -1)
+		return nil, failed
+	}
+	return checkColumns(db.mapperOrDefault(), reflect.TypeFor[T](), query, columns), nil
+}
+
+// checkColumns compares columns with the fields of t.
+func checkColumns(m *Mapper, t reflect.Type, query string, columns []*sql.ColumnType) *CheckReport {
+	r := &CheckReport{Query: query, Type: t}
+	base := derefType(t)
+	if isScannable(m, base) {
+		// only the first column has a destination
+		for i, c := range columns {
+			if i == 0 {
+				r.check(c, t)
+			} else {
+				r.MissingFields = append(r.MissingFields, c.Name())
+			}
+		}
+		return r
+	}
+
+	tm := m.TypeMap(base)
+	selected := make(map[string]bool, len(columns))
+	for _, c := range columns {
+		selected[c.Name()] = true
+		fi, ok := tm.Names[c.Name()]
+		if !ok {
+			r.MissingFields = append(r.MissingFields, c.Name())
+			continue
+		}
+		r.check(c, fi.Field.Type)
+	}
+
+	// whether a column of a nested struct is selected
+	selectedUnder := func(path string) bool {
+		for name := range selected {
+			if strings.HasPrefix(name, path+".") {
+				return true
+			}
+		}
+		return false
+	}
+fields:
+	for _, fi := range tm.Index {
+		// skip shadowed and embedded fields, and structs with fields
+		if tm.Names[fi.Path] != fi || selected[fi.Path] || !isScannable(m, derefType(fi.Field.Type)) {
+			continue
+		}
+		for p := fi.Parent; p != nil && p.Parent != nil; p = p.Parent {
+			// the fields of e.g. sql.NullString are not scanned into, and
+			// neither are those of nested structs without selected columns
+			if isScannable(m, derefType(p.Field.Type)) || strings.HasPrefix(fi.Path, p.Path+".") && !selectedUnder(p.Path) {
+				continue fields
+			}
+		}
+		r.UnusedFields = append(r.UnusedFields, fi.Path)
+	}
+	return r
+}
+
+var _timeType = reflect.TypeFor[time.Time]()
+
+// valueKind is the kind of value of a column or field, for the purpose of
+// checking that one can be scanned into the other.
+type valueKind int
+
+const (
+	kindUnknown valueKind = iota
+	kindInt
+	kindFloat
+	kindBool
+	kindText
+	kindTime
+)
+
+// kindOf returns the valueKind of t. The sql.Null types, and other structs
+// with a Valid field and a value field, have the kind of their value.
+func kindOf(t reflect.Type) (kind valueKind, nullable bool) {
+	if t.Kind() == reflect.Struct && t.NumField() == 2 {
+		if valid, ok := t.FieldByName("Valid"); ok && valid.Type.Kind() == reflect.Bool {
+			kind, _ := kindOf(t.Field(1 - valid.Index[0]).Type)
+			return kind, true
+		}
+	}
+	switch {
+	case t == _timeType:
+		return kindTime, false
+	case t.Kind() == reflect.String, t.Kind() == reflect.Slice && t.Elem().Kind() == reflect.Uint8:
+		return kindText, false
+	case t.Kind() == reflect.Bool:
+		return kindBool, false
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		return kindInt, false
+	case reflect.Float32, reflect.Float64:
+		return kindFloat, false
+	}
+	return kindUnknown, false
+}
+
+// check adds a TypeMismatch if c may not scan into a field of type field.
+func (r *CheckReport) check(c *sql.ColumnType, field reflect.Type) {
+	mismatch := func(nullable bool, reason string) {
+		r.Mismatches = append(r.Mismatches, TypeMismatch{
+			Column:       c.Name(),
+			DatabaseType: c.DatabaseTypeName(),
+			ScanType:     c.ScanType(),
+			Nullable:     nullable,
+			Field:        field,
+			Reason:       reason,
+		})
+	}
+	if field.Kind() == reflect.Interface {
+		return
+	}
+	scanType := c.ScanType()
+	if scanType == nil || scanType.Kind() == reflect.Interface || scanType.Kind() == reflect.Ptr && scanType.Elem().Kind() == reflect.Interface {
+		return // the driver doesn't report scan types
+	}
+	colKind, nullable := kindOf(scanType)
+	if n, ok := c.Nullable(); ok {
+		nullable = n
+	}
+
+	base := derefType(field)
+	isScanner := reflect.PointerTo(base).Implements(_scannerInterface)
+	canBeNull := field.Kind() == reflect.Ptr || isScanner || field.Kind() == reflect.Slice
+	if nullable && !canBeNull {
+		mismatch(nullable, "the column is nullable")
+		return
+	}
+	fieldKind, _ := kindOf(base)
+	if isScanner && fieldKind == kindUnknown {
+		return // the type scans values itself
+	}
+	if colKind == kindUnknown || fieldKind == kindUnknown || colKind == fieldKind || fieldKind == kindText {
+		return
+	}
+	switch {
+	case fieldKind == kindFloat && colKind == kindInt, fieldKind == kindBool && colKind == kindInt:
+		return
+	case fieldKind == kindFloat && colKind == kindText:
+		// DECIMAL values are reported as text by some drivers
+		if dbType := strings.ToUpper(c.DatabaseTypeName()); strings.Contains(dbType, "DECIMAL") || strings.Contains(dbType, "NUMERIC") {
+			return
+		}
+	}
+	mismatch(nullable, fmt.Sprintf("%s values cannot be converted to %s", scanType, base))
+}
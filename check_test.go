@@ -0,0 +1,232 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type checkAddress struct {
+	City string `db:"city"`
+}
+
+type checkPerson struct {
+	ID      int64          `db:"id"`
+	Name    string         `db:"name"`
+	Email   sql.NullString `db:"email"`
+	Score   float64        `db:"score"`
+	Born    time.Time      `db:"born"`
+	Address checkAddress   `db:"address"`
+}
+
+// checkCode is a sql.Scanner.
+type checkCode string
+
+func (c *checkCode) Scan(src any) error {
+	*c = checkCode(fmt.Sprint(src))
+	return nil
+}
+
+func TestCheck(t *testing.T) {
+	ctx := context.Background()
+	var (
+		int64Type      = reflect.TypeFor[int64]()
+		stringType     = reflect.TypeFor[string]()
+		nullStringType = reflect.TypeFor[sql.NullString]()
+		nullTimeType   = reflect.TypeFor[sql.NullTime]()
+	)
+	// the columns of the query are given in the query itself
+	results := map[string]fakeResult{
+		"valid": {
+			columns: []string{"id", "name", "email", "score", "born"},
+			types: []fakeColumnType{
+				{"BIGINT", int64Type, false},
+				{"VARCHAR", stringType, false},
+				{"VARCHAR", nullStringType, true},
+				{"DECIMAL", stringType, false},
+				{"DATETIME", nullTimeType, false},
+			},
+		},
+		"invalid": {
+			columns: []string{"id", "name", "address.city", "extra"},
+			types: []fakeColumnType{
+				{"VARCHAR", stringType, false},
+				{"VARCHAR", nullStringType, true},
+				{"VARCHAR", stringType, false},
+				{"INT", int64Type, false},
+			},
+		},
+		"untyped": {columns: []string{"id", "name", "email", "score", "born"}},
+	}
+	sqlDB, conn := newFakeDB(t, func(query string, _ []driver.NamedValue) (fakeResult, error) {
+		for name, res := range results {
+			if strings.Contains(query, "FROM "+name) {
+				return res, nil
+			}
+		}
+		return fakeResult{}, nil
+	})
+	db := NewDB(sqlDB, WithDialect(Postgres))
+
+	report, err := Check[checkPerson](ctx, db, "SELECT * FROM valid WHERE id IN (?);\n", []int64{1, 2})
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if !report.OK() || report.Err() != nil {
+		t.Fatalf("got %+v want no differences", report)
+	}
+	want := "SELECT * FROM (SELECT * FROM valid WHERE id IN ($1, $2)\n) AS dbx_check WHERE 1=0"
+	if got := conn.queries(); len(got) != 1 || got[0] != want {
+		t.Fatalf("got %q want %q", got, want)
+	}
+
+	report, err = Check[*checkPerson](ctx, db, "SELECT * FROM invalid")
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	wantReport := &CheckReport{
+		Query:         "SELECT * FROM invalid",
+		Type:          reflect.TypeFor[*checkPerson](),
+		MissingFields: []string{"extra"},
+		UnusedFields:  []string{"email", "score", "born"},
+		Mismatches: []TypeMismatch{
+			{Column: "id", DatabaseType: "VARCHAR", ScanType: stringType, Field: int64Type, Reason: "string values cannot be converted to int64"},
+			{Column: "name", DatabaseType: "VARCHAR", ScanType: nullStringType, Nullable: true, Field: stringType, Reason: "the column is nullable"},
+		},
+	}
+	if diff := cmp.Diff(report, wantReport, cmp.Comparer(func(a, b reflect.Type) bool { return a == b })); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+	if err := report.Err(); err == nil || !strings.Contains(err.Error(), "missing destination name extra in *dbx.checkPerson\n") ||
+		!strings.Contains(err.Error(), "column name (VARCHAR) cannot be scanned into string: the column is nullable") {
+		t.Fatalf("got %v want the differences", err)
+	}
+
+	// without scan types only names are compared
+	report, err = Check[checkPerson](ctx, db, "SELECT * FROM untyped")
+	if err != nil || !report.OK() {
+		t.Fatalf("got %+v, %+v want no differences", report, err)
+	}
+
+	// only one column is scanned into other types
+	report, err = Check[int64](ctx, db, "SELECT * FROM valid")
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if diff := cmp.Diff(report.MissingFields, []string{"name", "email", "score", "born"}); diff != "" || len(report.Mismatches) != 0 {
+		t.Fatalf("(-got +want) %s %+v", diff, report.Mismatches)
+	}
+	report, err = Check[time.Time](ctx, db, "SELECT * FROM valid")
+	if err != nil || len(report.Mismatches) != 1 {
+		t.Fatalf("got %+v, %+v want an int64 column scanned into time.Time", report, err)
+	}
+}
+
+func TestCheckKinds(t *testing.T) {
+	tests := []struct {
+		field    any
+		scanType any
+		dbType   string
+		nullable bool
+		ok       bool
+	}{
+		{field: "", scanType: int64(0), ok: true},
+		{field: []byte(nil), scanType: time.Time{}, ok: true},
+		{field: float64(0), scanType: int32(0), ok: true},
+		{field: float64(0), scanType: "", dbType: "DECIMAL", ok: true},
+		{field: float64(0), scanType: "", dbType: "VARCHAR"},
+		{field: false, scanType: int8(0), ok: true},
+		{field: int64(0), scanType: 1.5},
+		{field: time.Time{}, scanType: ""},
+		{field: time.Time{}, scanType: sql.NullTime{}, nullable: true},
+		{field: new(time.Time), scanType: sql.NullTime{}, nullable: true, ok: true},
+		{field: sql.Null[int64]{}, scanType: sql.NullInt64{}, nullable: true, ok: true},
+		{field: sql.NullInt64{}, scanType: ""},
+		{field: new(any), scanType: "", ok: true},
+		{field: checkCode(""), scanType: int64(0), ok: true},
+	}
+	for _, tt := range tests {
+		ctx := context.Background()
+		sqlDB, _ := newFakeDB(t, func(string, []driver.NamedValue) (fakeResult, error) {
+			return fakeResult{
+				columns: []string{"c"},
+				types:   []fakeColumnType{{tt.dbType, reflect.TypeOf(tt.scanType), tt.nullable}},
+			}, nil
+		})
+		rows, err := sqlDB.QueryContext(ctx, "SELECT c")
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		columns, err := rows.ColumnTypes()
+		_ = rows.Close()
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		var r CheckReport
+		r.check(columns[0], reflect.TypeOf(tt.field))
+		if got := len(r.Mismatches) == 0; got != tt.ok {
+			t.Errorf("got ok=%t want %t for %T from %T %s: %+v", got, tt.ok, tt.field, tt.scanType, tt.dbType, r.Mismatches)
+		}
+	}
+}
+
+var checkSchema = Schema{
+	create: `
+CREATE TABLE check_person (
+	id bigint NOT NULL,
+	name varchar(255) NOT NULL,
+	email varchar(255) NULL,
+	score decimal(10,2) NOT NULL,
+	born datetime NOT NULL
+);
+`,
+	drop: `
+drop table check_person;
+`,
+}
+
+func TestCheckMySQL(t *testing.T) {
+	RunWithSchemaContext(context.Background(), checkSchema, t, func(ctx context.Context, sqlDB *sql.DB, _ testing.TB) {
+		db := NewDB(sqlDB)
+
+		// the comment at the end must not comment out the wrapper
+		report, err := Check[checkPerson](ctx, db, "SELECT id, name, email, score, born FROM check_person WHERE id > ? -- all columns", 0)
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		if err := report.Err(); err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+
+		report, err = Check[*checkPerson](ctx, db, "SELECT name AS id, email AS name, 1 AS extra FROM check_person;")
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		if diff := cmp.Diff(report.MissingFields, []string{"extra"}); diff != "" {
+			t.Fatalf("(-got +want) %s", diff)
+		}
+		if diff := cmp.Diff(report.UnusedFields, []string{"email", "score", "born"}); diff != "" {
+			t.Fatalf("(-got +want) %s", diff)
+		}
+		var got []string
+		for _, m := range report.Mismatches {
+			got = append(got, m.Column+": "+m.Reason)
+		}
+		want := []string{"id: string values cannot be converted to int64", "name: the column is nullable"}
+		if diff := cmp.Diff(got, want); diff != "" {
+			t.Fatalf("(-got +want) %s", diff)
+		}
+
+		// the query is run, so errors of the database are returned
+		if _, err := Check[checkPerson](ctx, db, "SELECT missing FROM check_person"); err == nil {
+			t.Fatal("got nil want an error for the unknown column")
+		}
+	})
+}
@@ -0,0 +1,542 @@
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// analyzed is a query checked against a schema.
+type analyzed struct {
+	*query
+	sql     string      // the query with its parameters replaced by ?
+	columns []resultCol // the columns of the result of a SELECT
+	params  []*param    // the distinct parameters in order of appearance
+	args    []*param    // the parameter of each placeholder
+	tables  []*tableRef // the tables of the query
+	toks    []sqlToken
+}
+
+// resultCol is a column of the result of a query.
+type resultCol struct {
+	name string
+	typ  goType
+}
+
+// param is a :name parameter of a query.
+type param struct {
+	name string
+	typ  goType
+}
+
+// tableRef is a table referenced by a query.
+type tableRef struct {
+	*table
+	alias    string
+	nullable bool // true for the outer side of an outer join
+}
+
+// clauseKeywords end the list of tables of a FROM or UPDATE clause.
+var clauseKeywords = []string{"where", "group", "order", "limit", "having", "union", "set", "for", "window", "offset", "returning", "on", "using"}
+
+// joinKeywords may start a join.
+var joinKeywords = []string{"join", "inner", "left", "right", "full", "cross", "outer", "natural", "straight_join"}
+
+// analyze checks the tables, columns and parameters of q against s, and
+// infers the types of its result columns and parameters.
+func analyze(q *query, s schema) (*analyzed, error) {
+	toks, err := lex(q.src)
+	if err != nil {
+		return nil, fmt.Errorf("%s:%d: query %s: %w", q.file, q.line, q.name, err)
+	}
+	a := &analyzed{query: q, toks: toks}
+	if err := a.analyze(s); err != nil {
+		return nil, err
+	}
+	return a, nil
+}
+
+// errorf returns an error at the position of tok.
+func (a *analyzed) errorf(tok sqlToken, format string, args ...any) error {
+	line := a.line + lineOf(a.src, tok.pos) - 1
+	return fmt.Errorf("%s:%d: query %s: %s", a.file, line, a.name, fmt.Sprintf(format, args...))
+}
+
+// text returns the source of toks.
+func (a *analyzed) text(toks []sqlToken) string {
+	return a.src[toks[0].pos:toks[len(toks)-1].end]
+}
+
+func (a *analyzed) analyze(s schema) error {
+	var b strings.Builder
+	last := 0
+	for _, t := range a.toks {
+		switch {
+		case t.isPunct("?", "$"):
+			return a.errorf(t, "use :name parameters instead of %s", t.text)
+		case t.kind == tokParam:
+			b.WriteString(a.src[last:t.pos])
+			b.WriteByte('?')
+			last = t.end
+		}
+	}
+	b.WriteString(a.src[last:])
+	a.sql = b.String()
+
+	first := a.toks[0]
+	switch {
+	case first.is("select"):
+		if err := a.fromTables(s, a.indexTop(0, "from")); err != nil {
+			return err
+		}
+		if err := a.selectColumns(); err != nil {
+			return err
+		}
+	case first.is("delete"):
+		if err := a.fromTables(s, a.indexTop(0, "from")); err != nil {
+			return err
+		}
+	case first.is("update"):
+		if err := a.fromTables(s, 0); err != nil {
+			return err
+		}
+	case first.is("insert", "replace"):
+		if err := a.insertTable(s); err != nil {
+			return err
+		}
+	default:
+		return a.errorf(first, "unsupported statement %s; use SELECT, INSERT, REPLACE, UPDATE or DELETE", first.text)
+	}
+	if a.cmd != "exec" && !first.is("select") {
+		return a.errorf(first, ":%s queries must be SELECT statements", a.cmd)
+	}
+	return a.inferParams()
+}
+
+// indexTop returns the index of the first keyword after i that is outside
+// parentheses, or -1.
+func (a *analyzed) indexTop(i int, keyword string) int {
+	depth := 0
+	for ; i < len(a.toks); i++ {
+		switch t := a.toks[i]; {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case depth == 0 && t.is(keyword):
+			return i
+		}
+	}
+	return -1
+}
+
+// fromTables adds the tables that follow the keyword at i, which is FROM
+// or UPDATE, up to the next clause.
+func (a *analyzed) fromTables(s schema, i int) error {
+	if i < 0 {
+		return nil // e.g. SELECT 1
+	}
+	i++
+	nullable := false
+	for i < len(a.toks) {
+		t := a.toks[i]
+		if t.isPunct("(") {
+			return a.errorf(t, "subqueries in FROM are not supported")
+		}
+		name, next := qualifiedName(a.toks, i)
+		if name == "" {
+			return a.errorf(t, "expected a table name, found %s", t.text)
+		}
+		tbl, ok := s[strings.ToLower(name)]
+		if !ok {
+			return a.errorf(t, "table %s not found in the schema", name)
+		}
+		ref := &tableRef{table: tbl, alias: name, nullable: nullable}
+		i = next
+		if i < len(a.toks) && a.toks[i].is("as") {
+			i++
+		}
+		if i < len(a.toks) && a.toks[i].kind == tokIdent && !a.toks[i].is(clauseKeywords...) && !a.toks[i].is(joinKeywords...) {
+			ref.alias = a.toks[i].text
+			i++
+		}
+		a.tables = append(a.tables, ref)
+
+		// skip the join condition up to the next table
+		nullable = false
+		depth := 0
+	skip:
+		for ; i < len(a.toks); i++ {
+			t := a.toks[i]
+			switch {
+			case t.isPunct("("):
+				depth++
+			case t.isPunct(")"):
+				depth--
+			case depth > 0:
+			case t.isPunct(","), t.is("join", "straight_join"):
+				i++
+				break skip
+			case t.is("left"):
+				nullable = true
+			case t.is("right"):
+				for _, ref := range a.tables {
+					ref.nullable = true
+				}
+			case t.is("full"):
+				nullable = true
+				for _, ref := range a.tables {
+					ref.nullable = true
+				}
+			case t.is("where", "group", "order", "limit", "having", "union", "set", "for", "window", "offset", "returning"):
+				return nil
+			}
+		}
+	}
+	return nil
+}
+
+// insertTable adds the table of an INSERT or REPLACE statement.
+func (a *analyzed) insertTable(s schema) error {
+	i := a.indexTop(0, "into")
+	if i < 0 {
+		return a.errorf(a.toks[0], "expected INTO")
+	}
+	name, _ := qualifiedName(a.toks, i+1)
+	if name == "" {
+		return a.errorf(a.toks[i], "expected a table name after INTO")
+	}
+	tbl, ok := s[strings.ToLower(name)]
+	if !ok {
+		return a.errorf(a.toks[i+1], "table %s not found in the schema", name)
+	}
+	a.tables = append(a.tables, &tableRef{table: tbl, alias: name})
+	return nil
+}
+
+// resolve returns the column referenced by qualifier.name, where qualifier
+// is a table name or alias and may be empty.
+func (a *analyzed) resolve(qualifier string, name sqlToken) (*column, *tableRef, error) {
+	var found *column
+	var ref *tableRef
+	for _, r := range a.tables {
+		if qualifier != "" && !strings.EqualFold(r.alias, qualifier) {
+			continue
+		}
+		if c := r.column(name.text); c != nil {
+			if found != nil {
+				return nil, nil, a.errorf(name, "column %s is ambiguous", name.text)
+			}
+			found, ref = c, r
+		}
+	}
+	if found != nil {
+		return found, ref, nil
+	}
+	if qualifier != "" {
+		for _, r := range a.tables {
+			if strings.EqualFold(r.alias, qualifier) {
+				return nil, nil, a.errorf(name, "column %s not found in table %s", name.text, r.name)
+			}
+		}
+		return nil, nil, a.errorf(name, "table %s not found in the query", qualifier)
+	}
+	var names []string
+	for _, r := range a.tables {
+		names = append(names, r.name)
+	}
+	return nil, nil, a.errorf(name, "column %s not found in %s", name.text, strings.Join(names, ", "))
+}
+
+// columnRef returns the qualifier and name of toks if they reference a
+// column, i.e. they are name or qualifier.name.
+func columnRef(toks []sqlToken) (string, sqlToken, bool) {
+	switch {
+	case len(toks) == 1 && toks[0].kind == tokIdent:
+		return "", toks[0], true
+	case len(toks) == 3 && toks[0].kind == tokIdent && toks[1].isPunct(".") && toks[2].kind == tokIdent:
+		return toks[0].text, toks[2], true
+	}
+	return "", sqlToken{}, false
+}
+
+// selectColumns infers the result columns of a SELECT statement.
+func (a *analyzed) selectColumns() error {
+	end := a.indexTop(0, "from")
+	if end < 0 {
+		end = len(a.toks)
+	}
+	i := 1
+	for i < end && a.toks[i].is("distinct", "all", "distinctrow", "sql_calc_found_rows") {
+		i++
+	}
+	if i == end {
+		return a.errorf(a.toks[0], "no columns selected")
+	}
+	seen := map[string]bool{}
+	add := func(tok sqlToken, name string, typ goType) error {
+		if seen[strings.ToLower(name)] {
+			return a.errorf(tok, "column %s is selected twice; use an alias", name)
+		}
+		seen[strings.ToLower(name)] = true
+		a.columns = append(a.columns, resultCol{name: name, typ: typ})
+		return nil
+	}
+	addAll := func(tok sqlToken, refs []*tableRef) error {
+		for _, r := range refs {
+			for _, c := range r.columns {
+				typ, err := goTypeOf(c, !c.notNull || r.nullable)
+				if err != nil {
+					return a.errorf(tok, "%v", err)
+				}
+				if err := add(tok, c.name, typ); err != nil {
+					return err
+				}
+			}
+		}
+		return nil
+	}
+
+	for _, item := range splitTop(a.toks[i:end], ",") {
+		if len(item) == 0 {
+			return a.errorf(a.toks[i], "empty column in the select list")
+		}
+		// SELECT * and SELECT t.*
+		if len(item) == 1 && item[0].isPunct("*") {
+			if len(a.tables) == 0 {
+				return a.errorf(item[0], "SELECT * without tables")
+			}
+			if err := addAll(item[0], a.tables); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(item) == 3 && item[0].kind == tokIdent && item[1].isPunct(".") && item[2].isPunct("*") {
+			var refs []*tableRef
+			for _, r := range a.tables {
+				if strings.EqualFold(r.alias, item[0].text) {
+					refs = append(refs, r)
+				}
+			}
+			if len(refs) == 0 {
+				return a.errorf(item[0], "table %s not found in the query", item[0].text)
+			}
+			if err := addAll(item[0], refs); err != nil {
+				return err
+			}
+			continue
+		}
+
+		// an expression with an optional alias
+		expr, alias := item, ""
+		if n := len(item); n >= 3 && item[n-2].is("as") && item[n-1].kind == tokIdent {
+			expr, alias = item[:n-2], item[n-1].text
+		} else if n >= 2 && item[n-1].kind == tokIdent && (item[n-2].kind == tokIdent || item[n-2].isPunct(")")) {
+			expr, alias = item[:n-1], item[n-1].text
+		}
+		if qualifier, name, ok := columnRef(expr); ok {
+			c, ref, err := a.resolve(qualifier, name)
+			if err != nil {
+				return err
+			}
+			typ, err := goTypeOf(c, !c.notNull || ref.nullable)
+			if err != nil {
+				return a.errorf(name, "%v", err)
+			}
+			if alias == "" {
+				alias = c.name
+			}
+			if err := add(name, alias, typ); err != nil {
+				return err
+			}
+			continue
+		}
+		if len(expr) >= 3 && expr[0].is("count") && expr[1].isPunct("(") && expr[len(expr)-1].isPunct(")") {
+			if alias == "" {
+				return a.errorf(expr[0], "%s needs an alias", a.text(expr))
+			}
+			if err := add(expr[0], alias, goType{name: "int64"}); err != nil {
+				return err
+			}
+			continue
+		}
+		return a.errorf(expr[0], "cannot infer the type of %s; select columns of the schema or COUNT", a.text(expr))
+	}
+	return nil
+}
+
+// inferParams infers the types of the parameters of the query from the
+// columns they are compared with or assigned to.
+func (a *analyzed) inferParams() error {
+	assigned := a.assignedColumns()
+	byName := map[string]*param{}
+	clause := ""
+	for i, t := range a.toks {
+		if t.is("set", "where", "having", "on", "values", "update", "limit", "offset", "when", "select") {
+			clause = strings.ToLower(t.text)
+		}
+		if t.kind != tokParam {
+			continue
+		}
+		typ, err := a.paramType(i, clause, assigned)
+		if err != nil {
+			return err
+		}
+		p, ok := byName[t.text]
+		if !ok {
+			p = &param{name: t.text, typ: typ}
+			byName[t.text] = p
+			a.params = append(a.params, p)
+		} else if p.typ != typ {
+			return a.errorf(t, "parameter :%s is used as %s and %s", t.text, p.typ.name, typ.name)
+		}
+		a.args = append(a.args, p)
+	}
+	return nil
+}
+
+// assignedColumns returns the columns of an INSERT statement by the index
+// of the parameter that is inserted into them, for parameters that are
+// values of their own in VALUES.
+func (a *analyzed) assignedColumns() map[int]*column {
+	if !a.toks[0].is("insert", "replace") {
+		return nil
+	}
+	into := a.indexTop(0, "into")
+	values := a.indexTop(0, "values")
+	_, i := qualifiedName(a.toks, into+1)
+	if values < 0 || i >= values || !a.toks[i].isPunct("(") {
+		return nil
+	}
+	var columns []*column
+	for _, ref := range splitTop(a.toks[i+1:values-1], ",") {
+		if _, name, ok := columnRef(ref); ok {
+			columns = append(columns, a.tables[0].column(name.text))
+		} else {
+			columns = append(columns, nil)
+		}
+	}
+	assigned := map[int]*column{}
+	for j := values + 1; j < len(a.toks); j++ {
+		if !a.toks[j].isPunct("(") {
+			break
+		}
+		// find the closing parenthesis of the row
+		depth, end := 0, j
+		for ; end < len(a.toks); end++ {
+			if a.toks[end].isPunct("(") {
+				depth++
+			} else if a.toks[end].isPunct(")") {
+				if depth--; depth == 0 {
+					break
+				}
+			}
+		}
+		start := j + 1
+		for k, v := range splitTop(a.toks[start:end], ",") {
+			if len(v) == 1 && v[0].kind == tokParam && k < len(columns) && columns[k] != nil {
+				assigned[slices.IndexFunc(a.toks, func(t sqlToken) bool { return t.pos == v[0].pos })] = columns[k]
+			}
+		}
+		j = end + 1
+		if j >= len(a.toks) || !a.toks[j].isPunct(",") {
+			break
+		}
+	}
+	return assigned
+}
+
+// comparisons are the operators from which the type of a parameter is
+// inferred, e.g. in first_name = :name.
+var comparisons = []string{"=", "<>", "!=", "<", ">", "<=", ">="}
+
+// paramType returns the type of the parameter at toks[i] in the given
+// clause.
+func (a *analyzed) paramType(i int, clause string, assigned map[int]*column) (goType, error) {
+	t := a.toks[i]
+	at := func(j int) sqlToken {
+		if j < 0 || j >= len(a.toks) {
+			return sqlToken{kind: -1}
+		}
+		return a.toks[j]
+	}
+	// columnBefore and columnAfter return the column that ends at j or
+	// starts at j
+	columnBefore := func(j int) []sqlToken {
+		if at(j).kind != tokIdent {
+			return nil
+		}
+		if at(j-1).isPunct(".") && at(j-2).kind == tokIdent {
+			return a.toks[j-2 : j+1]
+		}
+		return a.toks[j : j+1]
+	}
+	columnAfter := func(j int) []sqlToken {
+		if at(j).kind != tokIdent {
+			return nil
+		}
+		if at(j+1).isPunct(".") && at(j+2).kind == tokIdent {
+			return a.toks[j : j+3]
+		}
+		return a.toks[j : j+1]
+	}
+	// set is true if the parameter is assigned to the column, in which case
+	// it is nullable if the column is
+	typeOf := func(ref []sqlToken, set, slice bool) (goType, error) {
+		qualifier, name, _ := columnRef(ref)
+		c, _, err := a.resolve(qualifier, name)
+		if err != nil {
+			return goType{}, err
+		}
+		typ, err := goTypeOf(c, set && !c.notNull)
+		if err != nil {
+			return goType{}, a.errorf(name, "%v", err)
+		}
+		if slice {
+			typ = typ.slice()
+		}
+		return typ, nil
+	}
+
+	if c, ok := assigned[i]; ok {
+		typ, err := goTypeOf(c, !c.notNull)
+		if err != nil {
+			return goType{}, a.errorf(t, "%v", err)
+		}
+		return typ, nil
+	}
+	switch {
+	case at(i-1).is("limit", "offset"), at(i-1).isPunct(",") && at(i-2).kind == tokParam && at(i-3).is("limit"):
+		return goType{name: "int64"}, nil
+	case at(i-1).isPunct(comparisons...) && columnBefore(i-2) != nil:
+		set := at(i-1).isPunct("=") && (clause == "set" || clause == "update")
+		return typeOf(columnBefore(i-2), set, false)
+	case at(i+1).isPunct(comparisons...) && columnAfter(i+2) != nil && !at(i+2+len(columnAfter(i+2))).isPunct("("):
+		return typeOf(columnAfter(i+2), false, false)
+	case at(i-1).is("like") && columnBefore(i-2) != nil:
+		return typeOf(columnBefore(i-2), false, false)
+	case at(i-1).isPunct("(") && at(i+1).isPunct(")") && at(i-2).is("in"):
+		j := i - 3
+		if at(j).is("not") {
+			j--
+		}
+		if ref := columnBefore(j); ref != nil {
+			return typeOf(ref, false, true)
+		}
+	case at(i-1).is("between") && columnBefore(i-2) != nil:
+		return typeOf(columnBefore(i-2), false, false)
+	case at(i-1).is("and") && at(i-2).kind == tokParam && at(i-3).is("between") && columnBefore(i-4) != nil:
+		return typeOf(columnBefore(i-4), false, false)
+	}
+	// a parameter named after a column of the query
+	var found []sqlToken
+	for _, r := range a.tables {
+		if r.column(t.text) != nil {
+			found = append(found, sqlToken{kind: tokIdent, text: t.text, pos: t.pos, end: t.end})
+		}
+	}
+	if len(found) == 1 {
+		return typeOf(found, clause == "values" || clause == "set", false)
+	}
+	return goType{}, a.errorf(t, "cannot infer the type of parameter :%s; compare it with a column", t.text)
+}
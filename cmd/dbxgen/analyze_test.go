@@ -0,0 +1,247 @@
+package main
+
+import (
+	"os"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+const testSchema = `
+-- comments and other statements are ignored
+SET NAMES utf8mb4;
+
+CREATE TABLE IF NOT EXISTS person (
+	id bigint NOT NULL AUTO_INCREMENT,
+	first_name varchar(255) NOT NULL,
+	email text,
+	active tinyint(1) NOT NULL DEFAULT 1,
+	score decimal(10,2),
+	avatar blob,
+	PRIMARY KEY (id),
+	UNIQUE KEY email (email)
+) ENGINE=InnoDB;
+
+CREATE TABLE "order" (
+	id serial,
+	person_id bigint NOT NULL REFERENCES person (id),
+	placed_at timestamptz NOT NULL
+);
+`
+
+func TestParseSchema(t *testing.T) {
+	s, err := parseSchema(testSchema)
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	got := map[string][]string{}
+	for name, tbl := range s {
+		for _, c := range tbl.columns {
+			typ, err := goTypeOf(c, !c.notNull)
+			if err != nil {
+				t.Fatalf("got %+v want nil", err)
+			}
+			got[name] = append(got[name], c.name+" "+typ.name)
+		}
+	}
+	want := map[string][]string{
+		"person": {"id int64", "first_name string", "email sql.NullString", "active bool", "score sql.NullFloat64", "avatar []byte"},
+		"order":  {"id int64", "person_id int64", "placed_at time.Time"},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+
+	for _, src := range []string{
+		"CREATE TABLE t (a int, a int)",
+		"CREATE TABLE t (a int); CREATE TABLE T (b int)",
+		"CREATE TABLE t AS SELECT 1",
+		"CREATE TABLE t (a int /* unterminated",
+	} {
+		if _, err := parseSchema(src); err == nil {
+			t.Errorf("got nil want error for %s", src)
+		}
+	}
+}
+
+func TestAnalyze(t *testing.T) {
+	s, err := parseSchema(testSchema)
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	tests := []struct {
+		name    string
+		cmd     string
+		src     string
+		sql     string
+		columns []string // name type
+		args    []string // name type
+		err     string
+	}{
+		{
+			name:    "star",
+			src:     "SELECT * FROM person WHERE id = :id",
+			sql:     "SELECT * FROM person WHERE id = ?",
+			columns: []string{"id int64", "first_name string", "email sql.NullString", "active bool", "score sql.NullFloat64", "avatar []byte"},
+			args:    []string{"id int64"},
+		},
+		{
+			name:    "join with aliases",
+			src:     "SELECT o.id AS order_id, p.first_name name, `o`.placed_at FROM `order` o JOIN person AS p ON p.id = o.person_id WHERE p.email = :email AND o.placed_at BETWEEN :from AND :to",
+			sql:     "SELECT o.id AS order_id, p.first_name name, `o`.placed_at FROM `order` o JOIN person AS p ON p.id = o.person_id WHERE p.email = ? AND o.placed_at BETWEEN ? AND ?",
+			columns: []string{"order_id int64", "name string", "placed_at time.Time"},
+			args:    []string{"email string", "from time.Time", "to time.Time"},
+		},
+		{
+			name: "duplicate column",
+			src:  "SELECT person.id, o.* FROM person LEFT JOIN \"order\" o ON o.person_id = person.id",
+			err:  "column id is selected twice; use an alias",
+		},
+		{
+			name:    "left join",
+			src:     "SELECT person.id, o.person_id FROM person LEFT JOIN \"order\" o ON o.person_id = person.id",
+			columns: []string{"id int64", "person_id sql.NullInt64"},
+		},
+		{
+			name:    "right join",
+			src:     "SELECT o.placed_at FROM \"order\" o RIGHT JOIN person p ON o.person_id = p.id WHERE :id = p.id LIMIT :offset, :limit",
+			columns: []string{"placed_at sql.NullTime"},
+			args:    []string{"id int64", "offset int64", "limit int64"},
+		},
+		{
+			name:    "count and in",
+			src:     "SELECT COUNT(*) AS n FROM person WHERE id NOT IN (:ids) AND first_name LIKE :prefix AND id = :ids2 OR id = :id",
+			columns: []string{"n int64"},
+			args:    []string{"ids []int64", "prefix string", "ids2 int64", "id int64"},
+		},
+		{
+			name: "insert",
+			cmd:  "exec",
+			src:  "INSERT INTO person (first_name, email, score) VALUES (:first_name, :email, 1), (:first_name, LOWER(:email), :score) ON DUPLICATE KEY UPDATE email = :email",
+			args: []string{"first_name string", "email sql.NullString", "first_name string", "email sql.NullString", "score sql.NullFloat64", "email sql.NullString"},
+		},
+		{
+			name: "update and repeated parameters",
+			cmd:  "exec",
+			src:  "UPDATE person SET email = :email, active = :active WHERE email = :old_email AND id = :id AND id <> :id",
+			args: []string{"email sql.NullString", "active bool", "old_email string", "id int64", "id int64"},
+		},
+		{name: "positional parameter", src: "SELECT id FROM person WHERE id = ?", err: "use :name parameters instead of ?"},
+		{name: "unknown table", src: "SELECT id FROM people", err: "table people not found in the schema"},
+		{name: "unknown column", src: "SELECT id,\n\tlast_name FROM person", err: "people.sql:3: query Q: column last_name not found in person"},
+		{name: "unknown qualified column", src: "SELECT p.last_name FROM person p", err: "column last_name not found in table person"},
+		{name: "unknown qualifier", src: "SELECT x.id FROM person p", err: "table x not found in the query"},
+		{name: "ambiguous column", src: "SELECT id FROM person JOIN \"order\" ON person_id = person.id", err: "column id is ambiguous"},
+		{name: "expression", src: "SELECT id + 1 AS next FROM person", err: "cannot infer the type of id + 1"},
+		{name: "count without alias", src: "SELECT COUNT(*) FROM person", err: "COUNT(*) needs an alias"},
+		{name: "subquery", src: "SELECT id FROM (SELECT id FROM person) p", err: "subqueries in FROM are not supported"},
+		{name: "untyped parameter", src: "SELECT id FROM person WHERE LENGTH(first_name) > :n", err: "cannot infer the type of parameter :n"},
+		{name: "conflicting parameter", src: "SELECT id FROM person WHERE id = :x OR first_name = :x", err: "parameter :x is used as int64 and string"},
+		{name: "exec as many", src: "DELETE FROM person", err: ":many queries must be SELECT statements"},
+		{name: "unsupported statement", src: "WITH p AS (SELECT 1) SELECT * FROM p", err: "unsupported statement WITH"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cmd := tt.cmd
+			if cmd == "" {
+				cmd = "many"
+			}
+			a, err := analyze(&query{name: "Q", cmd: cmd, src: tt.src, file: "people.sql", line: 2}, s)
+			if tt.err != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.err) {
+					t.Fatalf("got %v want error containing %q", err, tt.err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got %+v want nil", err)
+			}
+			var columns, args []string
+			for _, c := range a.columns {
+				columns = append(columns, c.name+" "+c.typ.name)
+			}
+			for _, p := range a.args {
+				args = append(args, p.name+" "+p.typ.name)
+			}
+			if diff := cmp.Diff(columns, tt.columns); diff != "" {
+				t.Errorf("columns (-got +want) %s", diff)
+			}
+			if diff := cmp.Diff(args, tt.args); diff != "" {
+				t.Errorf("args (-got +want) %s", diff)
+			}
+			if tt.sql != "" && a.sql != tt.sql {
+				t.Errorf("got %s want %s", a.sql, tt.sql)
+			}
+		})
+	}
+}
+
+func TestParseQueries(t *testing.T) {
+	src := `-- queries of people
+
+-- name: GetPerson :one
+-- GetPerson returns a person.
+
+SELECT *
+FROM person -- all columns
+WHERE id = :id;
+
+-- name: DeletePeople :exec
+DELETE FROM person
+`
+	queries, err := parseQueries("people.sql", src)
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	got := make([]query, len(queries))
+	for i, q := range queries {
+		got[i] = *q
+	}
+	want := []query{
+		{name: "GetPerson", cmd: "one", doc: []string{"GetPerson returns a person."}, src: "SELECT *\nFROM person -- all columns\nWHERE id = :id", file: "people.sql", line: 6},
+		{name: "DeletePeople", cmd: "exec", src: "DELETE FROM person", file: "people.sql", line: 11},
+	}
+	if diff := cmp.Diff(got, want, cmp.AllowUnexported(query{})); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+
+	for _, src := range []string{
+		"SELECT 1;",
+		"-- name: Q :all\nSELECT 1",
+		"-- name: Q :one\n",
+	} {
+		if _, err := parseQueries("q.sql", src); err == nil {
+			t.Errorf("got nil want error for %q", src)
+		}
+	}
+}
+
+func TestGenerateQueriesErrors(t *testing.T) {
+	dir := t.TempDir()
+	write := func(name, content string) string {
+		name = dir + "/" + name
+		if err := os.WriteFile(name, []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+		return name
+	}
+	schema := write("schema.sql", testSchema)
+	write("a.sql", "-- name: GetPerson :one\nSELECT id FROM person WHERE id = :id\n")
+	write("b.sql", "-- name: GetPerson :one\nSELECT id FROM person\n")
+	write("c.sql", "-- name: getPerson :one\nSELECT id FROM person\n")
+
+	tests := []struct {
+		pattern string
+		err     string
+	}{
+		{pattern: dir + "/[ab].sql", err: "b.sql:1: query GetPerson is already defined at " + dir + "/a.sql:1"},
+		{pattern: dir + "/c.sql", err: "query name getPerson is not an exported Go identifier"},
+		{pattern: dir + "/d*.sql", err: "no files match"},
+	}
+	for _, tt := range tests {
+		if _, err := generateQueries("p", schema, tt.pattern); err == nil || !strings.Contains(err.Error(), tt.err) {
+			t.Errorf("got %v want error containing %q", err, tt.err)
+		}
+	}
+}
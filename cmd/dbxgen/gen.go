@@ -0,0 +1,291 @@
+package main
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"go/ast"
+	"go/format"
+	"go/parser"
+	"go/token"
+	"go/types"
+	"path/filepath"
+	"reflect"
+	"slices"
+	"strings"
+
+	"golang.org/x/tools/go/packages"
+)
+
+// generate returns the source of the ScanColumns methods of the named
+// types of the package in dir, to be written to output.
+func generate(dir, output string, typeNames []string) ([]byte, error) {
+	pkg, err := loadPackage(dir, output)
+	if err != nil {
+		return nil, err
+	}
+	g := &generator{pkg: pkg.Types, name: pkg.Types.Name(), imports: map[string]string{"fmt": "fmt"}}
+	for _, name := range typeNames {
+		if err := g.scanColumns(name); err != nil {
+			return nil, err
+		}
+	}
+	return g.source()
+}
+
+// loadPackage loads the package in dir with its syntax and types. The
+// output file is ignored, except for its package clause, so that a file
+// that no longer compiles against the current types can be regenerated.
+func loadPackage(dir, output string) (*packages.Package, error) {
+	output, err := filepath.Abs(output)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &packages.Config{
+		Mode: packages.NeedName | packages.NeedImports | packages.NeedDeps | packages.NeedTypes | packages.NeedSyntax | packages.NeedTypesInfo,
+		Dir:  dir,
+		ParseFile: func(fset *token.FileSet, filename string, src []byte) (*ast.File, error) {
+			mode := parser.AllErrors | parser.ParseComments
+			if filename == output {
+				mode = parser.PackageClauseOnly
+			}
+			return parser.ParseFile(fset, filename, src, mode)
+		},
+	}
+	pkgs, err := packages.Load(cfg, ".")
+	if err != nil {
+		return nil, err
+	}
+	if len(pkgs) != 1 {
+		return nil, fmt.Errorf("%d packages found in %s", len(pkgs), dir)
+	}
+	var errs []error
+	for _, err := range pkgs[0].Errors {
+		errs = append(errs, err)
+	}
+	if len(errs) > 0 {
+		return nil, errors.Join(errs...)
+	}
+	return pkgs[0], nil
+}
+
+// generator writes the generated code of a package.
+type generator struct {
+	pkg     *types.Package    // nil when generating queries
+	name    string            // the package name
+	imports map[string]string // package names by path
+	buf     bytes.Buffer
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.buf, format, args...)
+}
+
+// source returns the formatted source of the generated file.
+func (g *generator) source() ([]byte, error) {
+	var b bytes.Buffer
+	fmt.Fprintf(&b, "// Code generated by dbxgen; DO NOT EDIT.\n\npackage %s\n\nimport (\n", g.name)
+	paths := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		paths = append(paths, path)
+	}
+	// standard library packages first, as goimports does
+	slices.SortFunc(paths, func(a, b string) int {
+		if ai, bi := isStd(a), isStd(b); ai != bi {
+			if ai {
+				return -1
+			}
+			return 1
+		}
+		return strings.Compare(a, b)
+	})
+	for i, path := range paths {
+		if i > 0 && isStd(paths[i-1]) && !isStd(path) {
+			b.WriteString("\n")
+		}
+		if name := g.imports[path]; name != filepath.Base(path) {
+			fmt.Fprintf(&b, "\t%s %q\n", name, path)
+		} else {
+			fmt.Fprintf(&b, "\t%q\n", path)
+		}
+	}
+	b.WriteString(")\n")
+	b.Write(g.buf.Bytes())
+	src, err := format.Source(b.Bytes())
+	if err != nil {
+		return nil, fmt.Errorf("generated invalid code: %w\n%s", err, b.Bytes())
+	}
+	return src, nil
+}
+
+// isStd reports whether path is the import path of a standard library
+// package, whose first element has no dot.
+func isStd(path string) bool {
+	first, _, _ := strings.Cut(path, "/")
+	return !strings.Contains(first, ".")
+}
+
+// qualifier qualifies types of other packages by their name and records
+// their import.
+func (g *generator) qualifier(p *types.Package) string {
+	if p == g.pkg {
+		return ""
+	}
+	g.imports[p.Path()] = p.Name()
+	return p.Name()
+}
+
+// scanColumns writes the ScanColumns method of the named struct type.
+func (g *generator) scanColumns(name string) error {
+	obj, ok := g.pkg.Scope().Lookup(name).(*types.TypeName)
+	if !ok {
+		return fmt.Errorf("type %s not found in package %s", name, g.pkg.Path())
+	}
+	named, ok := obj.Type().(*types.Named)
+	if !ok || named.TypeParams().Len() > 0 {
+		return fmt.Errorf("%s must be a defined, non-generic type", name)
+	}
+	st, ok := named.Underlying().(*types.Struct)
+	if !ok {
+		return fmt.Errorf("%s is not a struct type", name)
+	}
+
+	g.printf("\n// ScanColumns implements dbx.ColumnScanner.\n")
+	g.printf("func (v *%s) ScanColumns(columns []string) ([]any, error) {\n", name)
+	g.printf("dest := make([]any, len(columns))\n")
+	g.printf("for i, column := range columns {\n")
+	g.printf("switch column {\n")
+	for _, fi := range columnsOf(st) {
+		g.printf("case %q:\n", fi.path)
+		// allocate nil pointers on the way to the field
+		chain := fi.chain()
+		sel := "v"
+		for i, f := range chain {
+			if f.pkg != nil && f.pkg != g.pkg && !token.IsExported(f.goName) {
+				return fmt.Errorf("column %s of %s: field %s of package %s is not accessible", fi.path, name, f.goName, f.pkg.Path())
+			}
+			sel += "." + f.goName
+			if ptr, ok := f.typ.(*types.Pointer); ok && i < len(chain)-1 {
+				g.printf("if %s == nil {\n%s = new(%s)\n}\n", sel, sel, types.TypeString(ptr.Elem(), g.qualifier))
+			}
+		}
+		g.printf("dest[i] = &%s\n", sel)
+	}
+	g.printf("default:\n")
+	g.printf("return nil, fmt.Errorf(\"missing destination name %%s in %%T\", column, v)\n")
+	g.printf("}\n}\n")
+	g.printf("return dest, nil\n}\n")
+	return nil
+}
+
+// fieldInfo is a field of a struct, as in dbx.FieldInfo.
+type fieldInfo struct {
+	path     string // the column name
+	name     string
+	goName   string
+	pkg      *types.Package // the package of the field, for unexported fields
+	typ      types.Type
+	embedded bool
+	parent   *fieldInfo // nil for the root
+}
+
+func (fi *fieldInfo) isRecursive() bool {
+	for p := fi.parent; p != nil; p = p.parent {
+		if p.typ != nil && fi.typ != nil && types.Identical(fi.typ, p.typ) {
+			return true
+		}
+	}
+	return false
+}
+
+// chain returns the fields from the outermost struct to fi.
+func (fi *fieldInfo) chain() []*fieldInfo {
+	var chain []*fieldInfo
+	for f := fi; f.parent != nil; f = f.parent {
+		chain = append(chain, f)
+	}
+	slices.Reverse(chain)
+	return chain
+}
+
+// columnsOf returns the fields of st that are mapped to columns, using the
+// same rules as dbx.DefaultMapper: a breadth-first search of the fields,
+// where the first field with a given path wins unless it is embedded.
+func columnsOf(st *types.Struct) []*fieldInfo {
+	type item struct {
+		st         *types.Struct // nil if the field is not a struct
+		fi         *fieldInfo
+		parentPath string
+	}
+	queue := []item{{st, &fieldInfo{}, ""}}
+	var index []*fieldInfo
+	for len(queue) > 0 {
+		it := queue[0]
+		queue = queue[1:]
+		if it.st == nil || it.fi.isRecursive() {
+			continue
+		}
+		for i := range it.st.NumFields() {
+			f := it.st.Field(i)
+			if !f.Exported() && !f.Embedded() {
+				continue
+			}
+			tag, name := parseName(f.Name(), it.st.Tag(i))
+			if name == "-" {
+				continue
+			}
+			fi := &fieldInfo{name: name, goName: f.Name(), typ: f.Type(), parent: it.fi}
+			if !f.Exported() {
+				fi.pkg = f.Pkg()
+			}
+			fi.path = name
+			if it.parentPath != "" {
+				fi.path = it.parentPath + "." + name
+			}
+			if f.Embedded() {
+				// embedded structs without a tag are flattened
+				pp := it.parentPath
+				if tag != "" {
+					pp = fi.path
+				}
+				fi.embedded = true
+				queue = append(queue, item{structOf(f.Type()), fi, pp})
+			} else if s := structOf(f.Type()); s != nil {
+				queue = append(queue, item{s, fi, fi.path})
+			}
+			index = append(index, fi)
+		}
+	}
+
+	paths := map[string]*fieldInfo{}
+	var columns []*fieldInfo
+	for _, fi := range index {
+		if prev, ok := paths[fi.path]; !ok || prev.embedded {
+			paths[fi.path] = fi
+			if fi.name != "" && !fi.embedded {
+				columns = append(columns, fi)
+			}
+		}
+	}
+	return columns
+}
+
+// parseName returns the db tag and the column name of a field, as
+// dbx.DefaultMapper does.
+func parseName(goName, tag string) (dbTag, name string) {
+	if !strings.Contains(tag, "db:") {
+		return "", strings.ToLower(goName)
+	}
+	dbTag = reflect.StructTag(tag).Get("db")
+	name, _, _ = strings.Cut(dbTag, ",")
+	return dbTag, name
+}
+
+// structOf returns the struct type of t or of the type t points to, or nil.
+func structOf(t types.Type) *types.Struct {
+	if p, ok := t.Underlying().(*types.Pointer); ok {
+		t = p.Elem()
+	}
+	st, _ := t.Underlying().(*types.Struct)
+	return st
+}
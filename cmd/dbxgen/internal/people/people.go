@@ -0,0 +1,53 @@
+// Package people has example types for dbxgen. Its generated code is
+// checked against the reflection-based mapping of dbx.
+package people
+
+import (
+	"database/sql"
+	"time"
+)
+
+//go:generate go run github.com/Jimeux/dbx/cmd/dbxgen -type Person,Team
+
+// Audit is embedded without a tag, so its fields are flattened.
+type Audit struct {
+	CreatedAt time.Time `db:"created_at"`
+	UpdatedAt *time.Time
+}
+
+// address is embedded with a tag, so its fields are prefixed.
+type address struct {
+	City    string
+	Country string `db:"country_code"`
+}
+
+// Name is a sql.Scanner.
+type Name string
+
+func (n *Name) Scan(src any) error {
+	switch src := src.(type) {
+	case string:
+		*n = Name(src)
+	case []byte:
+		*n = Name(src)
+	}
+	return nil
+}
+
+type Person struct {
+	ID       int64 `db:"id,pk"`
+	Name     Name
+	Email    sql.NullString `db:"email"`
+	Password string         `db:"-"`
+	internal string
+	Audit
+	address `db:"address"`
+	Manager *Person `db:"manager"`
+}
+
+type Team struct {
+	ID     int64 `db:"id"`
+	Lead   *Person
+	Deputy Person `db:"deputy"`
+	*Audit
+}
@@ -0,0 +1,64 @@
+package people
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/Jimeux/dbx"
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestScanColumns checks that the generated ScanColumns methods return the
+// same fields as the reflection-based mapping of dbx.DefaultMapper.
+func TestScanColumns(t *testing.T) {
+	for _, v := range []dbx.ColumnScanner{&Person{}, &Team{}} {
+		typ := reflect.TypeOf(v).Elem()
+		t.Run(typ.Name(), func(t *testing.T) {
+			var names []string
+			for name := range dbx.DefaultMapper.TypeMap(typ).Names {
+				names = append(names, name)
+			}
+			for _, name := range names {
+				generated := reflect.New(typ)
+				dest, err := generated.Interface().(dbx.ColumnScanner).ScanColumns([]string{name})
+				if err != nil {
+					t.Fatalf("got %+v want nil for %s", err, name)
+				}
+
+				// walking the traversal of the same value must find the same
+				// field, and allocate the same nil pointers on a new value
+				if got, want := dest[0], addr(generated, name); got != want {
+					t.Errorf("got %p (%T) want %p (%T) for %s", got, got, want, want, name)
+				}
+				reflected := reflect.New(typ)
+				addr(reflected, name)
+				if diff := cmp.Diff(generated.Interface(), reflected.Interface(), cmp.AllowUnexported(Person{})); diff != "" {
+					t.Errorf("(-generated +reflected) for %s: %s", name, diff)
+				}
+			}
+			if len(names) == 0 {
+				t.Fatal("got no columns")
+			}
+		})
+	}
+
+	if _, err := (&Person{}).ScanColumns([]string{"id", "password"}); err == nil {
+		t.Fatal("got nil want error for unknown column")
+	}
+}
+
+// addr returns a pointer to the field of v named name by dbx.DefaultMapper,
+// allocating nil pointers on the way as dbx does.
+func addr(v reflect.Value, name string) any {
+	f := v.Elem()
+	for _, i := range dbx.DefaultMapper.TypeMap(f.Type()).Names[name].Traversal {
+		if f.Kind() == reflect.Ptr {
+			if f.IsNil() {
+				f.Set(reflect.New(f.Type().Elem()))
+			}
+			f = f.Elem()
+		}
+		f = f.Field(i)
+	}
+	return f.Addr().Interface()
+}
@@ -0,0 +1,30 @@
+-- name: GetPerson :one
+SELECT * FROM person WHERE id = :id;
+
+-- name: ListPeople :many
+-- ListPeople returns the people with one of the given last names,
+-- ordered by first name.
+SELECT id, first_name, last_name, email
+FROM person
+WHERE last_name IN (:last_names)
+ORDER BY first_name
+LIMIT :limit;
+
+-- name: CountPeople :one
+SELECT COUNT(*) AS n FROM person WHERE added_at >= :since;
+
+-- name: ListResidents :many
+SELECT p.first_name, pl.city, r.since AS resident_since
+FROM place pl
+LEFT JOIN residence r ON r.place_id = pl.id
+LEFT JOIN person p ON p.id = r.person_id
+WHERE pl.country = :country;
+
+-- name: InsertPerson :exec
+INSERT INTO person (first_name, last_name, email) VALUES (:first_name, :last_name, :email);
+
+-- name: UpdateEmail :exec
+UPDATE person SET email = :email WHERE id = :id;
+
+-- name: DeletePerson :exec
+DELETE FROM person WHERE id = :id;
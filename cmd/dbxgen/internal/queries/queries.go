@@ -0,0 +1,5 @@
+// Package queries has example queries for dbxgen. Its generated code is
+// checked by the tests of dbxgen.
+package queries
+
+//go:generate go run github.com/Jimeux/dbx/cmd/dbxgen -queries *.sql -schema schema.sql
@@ -0,0 +1,24 @@
+CREATE TABLE person (
+	id bigint NOT NULL AUTO_INCREMENT,
+	first_name varchar(255) NOT NULL,
+	last_name varchar(255) NOT NULL,
+	email varchar(255),
+	added_at timestamp NOT NULL DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (id)
+) ENGINE=InnoDB;
+
+CREATE TABLE place (
+	id bigint NOT NULL AUTO_INCREMENT PRIMARY KEY,
+	country varchar(255) NOT NULL,
+	city varchar(255) NULL,
+	telcode integer NOT NULL
+);
+
+CREATE TABLE residence (
+	person_id bigint NOT NULL,
+	place_id bigint NOT NULL,
+	since date,
+	PRIMARY KEY (person_id, place_id),
+	CONSTRAINT fk_person FOREIGN KEY (person_id) REFERENCES person (id),
+	CONSTRAINT fk_place FOREIGN KEY (place_id) REFERENCES place (id)
+);
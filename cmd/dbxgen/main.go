@@ -0,0 +1,179 @@
+// Command dbxgen generates code that uses dbx without reflection. It has
+// two modes: it generates ScanColumns methods for struct types, or typed
+// functions for the queries of .sql files.
+//
+// Usage:
+//
+//	dbxgen -type Person,Order [-output file] [-check] [dir]
+//	dbxgen -queries 'queries/*.sql' -schema schema.sql [-output file] [-check] [dir]
+//
+// # Types
+//
+// With -type, dbxgen generates ScanColumns methods, which implement
+// dbx.ColumnScanner, for struct types with db tags. Get and Select use the
+// generated methods to find the fields to scan columns into, unless the DB
+// uses a Mapper with other rules than dbx.DefaultMapper.
+//
+// The columns of a type are the same as with dbx.DefaultMapper: fields are
+// named by their db tag or their lowercased name, embedded structs are
+// flattened unless they are tagged, and the fields of nested structs are
+// named by their dotted path, e.g. "address.city". Nil pointers on the way
+// to a field are allocated when it is scanned.
+//
+// The output is written to <type>_dbx.go in dir, which defaults to the
+// current directory.
+//
+// # Queries
+//
+// With -queries, dbxgen reads the .sql files that match the pattern. Each
+// query starts with a comment that gives its name and what it returns:
+//
+//	-- name: ListPeople :many
+//	-- ListPeople returns the people with the given last name.
+//	SELECT id, first_name FROM person WHERE last_name = :last_name;
+//
+// A :many query becomes a function that returns a dbx.Scanner of its rows,
+// a :one query calls dbx.Get, and an :exec query calls dbx.Exec. The
+// comments after the name are used as the documentation of the function.
+// The rows are structs with a field per column, or the type of the column
+// if only one is selected, and the :name parameters are the fields of a
+// Params struct. The output is written to queries_dbx.go in dir.
+//
+// The queries are checked against the CREATE TABLE statements of the
+// schema file without a database: the tables and columns they use must
+// exist, and the types of the columns and parameters are inferred from the
+// schema. Columns may be selected by name, with * or t.*, or with COUNT,
+// and parameters must be compared with or assigned to columns, or be
+// named after one. Columns of the outer side of an outer join and columns
+// that are not NOT NULL use the sql.Null types.
+//
+// # Check
+//
+// With -check, the output is not written; instead dbxgen exits with an
+// error if the file is missing or out of date.
+//
+// dbxgen is usually run with go generate:
+//
+//	//go:generate go run github.com/Jimeux/dbx/cmd/dbxgen -type Person
+package main
+
+import (
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"go/parser"
+	"go/token"
+	"io"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+func main() {
+	log.SetFlags(0)
+	log.SetPrefix("dbxgen: ")
+	if err := run(os.Args[1:], os.Stderr); err != nil {
+		log.Fatal(err)
+	}
+}
+
+func run(args []string, stderr io.Writer) error {
+	fs := flag.NewFlagSet("dbxgen", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	typeNames := fs.String("type", "", "comma-separated list of struct type names")
+	queries := fs.String("queries", "", "glob pattern of the .sql files of queries")
+	schemaFile := fs.String("schema", "", "file with the CREATE TABLE statements of the tables used by -queries")
+	output := fs.String("output", "", "output file name; default <dir>/<type>_dbx.go or <dir>/queries_dbx.go")
+	check := fs.Bool("check", false, "check that the output file is up to date instead of writing it")
+	fs.Usage = func() {
+		fmt.Fprintln(stderr, "Usage: dbxgen -type T[,T...] [-output file] [-check] [dir]")
+		fmt.Fprintln(stderr, "       dbxgen -queries pattern -schema file [-output file] [-check] [dir]")
+		fs.PrintDefaults()
+	}
+	if err := fs.Parse(args); err != nil {
+		return err
+	}
+	if (*typeNames == "") == (*queries == "") || (*queries == "") != (*schemaFile == "") || fs.NArg() > 1 {
+		fs.Usage()
+		return errors.New("invalid arguments")
+	}
+	dir := "."
+	if fs.NArg() == 1 {
+		dir = fs.Arg(0)
+	}
+
+	var src []byte
+	var err error
+	if *queries != "" {
+		if *output == "" {
+			*output = filepath.Join(dir, "queries_dbx.go")
+		}
+		var name string
+		if name, err = packageName(dir, *output); err == nil {
+			src, err = generateQueries(name, *schemaFile, *queries)
+		}
+	} else {
+		types := strings.Split(*typeNames, ",")
+		if *output == "" {
+			*output = filepath.Join(dir, strings.ToLower(types[0])+"_dbx.go")
+		}
+		src, err = generate(dir, *output, types)
+	}
+	if err != nil {
+		return err
+	}
+	if *check {
+		return checkFile(*output, src)
+	}
+	return os.WriteFile(*output, src, 0o644)
+}
+
+// packageName returns the name of the package of the Go files in dir other
+// than output and tests, or the name of dir if it has none.
+func packageName(dir, output string) (string, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.go"))
+	if err != nil {
+		return "", err
+	}
+	for _, file := range files {
+		if filepath.Clean(file) == filepath.Clean(output) || strings.HasSuffix(file, "_test.go") {
+			continue
+		}
+		f, err := parser.ParseFile(token.NewFileSet(), file, nil, parser.PackageClauseOnly)
+		if err != nil {
+			return "", err
+		}
+		return f.Name.Name, nil
+	}
+	abs, err := filepath.Abs(dir)
+	if err != nil {
+		return "", err
+	}
+	name := strings.Map(func(r rune) rune {
+		if r == '-' || r == '.' {
+			return '_'
+		}
+		return r
+	}, filepath.Base(abs))
+	if !token.IsIdentifier(name) {
+		return "", fmt.Errorf("cannot name the package of %s", dir)
+	}
+	return name, nil
+}
+
+// checkFile returns an error if the file name doesn't contain src.
+func checkFile(name string, src []byte) error {
+	old, err := os.ReadFile(name)
+	if errors.Is(err, os.ErrNotExist) {
+		return fmt.Errorf("%s is missing: run dbxgen to generate it", name)
+	}
+	if err != nil {
+		return err
+	}
+	if !bytes.Equal(old, src) {
+		return fmt.Errorf("%s is out of date: run dbxgen to update it", name)
+	}
+	return nil
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"io"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"testing"
+)
+
+func TestCheck(t *testing.T) {
+	// the generated code of the example package is up to date
+	if err := run([]string{"-type", "Person,Team", "-check", "internal/people"}, io.Discard); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+
+	if err := run([]string{"-queries", "internal/queries/*.sql", "-schema", "internal/queries/schema.sql", "-check", "internal/queries"}, io.Discard); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+
+	// a copy of the package in its own module
+	dir := t.TempDir()
+	src, err := os.ReadFile("internal/people/people.go")
+	if err != nil {
+		t.Fatal(err)
+	}
+	write := func(name, content string) {
+		if err := os.WriteFile(filepath.Join(dir, name), []byte(content), 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	write("go.mod", "module example.com/people\n\ngo 1.23\n")
+	write("people.go", string(src))
+
+	args := []string{"-type", "Person,Team", "-check", dir}
+	if err := run(args, io.Discard); err == nil || !strings.Contains(err.Error(), "is missing") {
+		t.Fatalf("got %v want missing error", err)
+	}
+	if err := run(append(args[:2:2], dir), io.Discard); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if err := run(args, io.Discard); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+
+	// a new field makes the output stale, even though it still compiles
+	write("people.go", strings.Replace(string(src), "\tManager *Person `db:\"manager\"`", "\tManager *Person `db:\"manager\"`\n\tPhone string", 1))
+	if err := run(args, io.Discard); err == nil || !strings.Contains(err.Error(), "is out of date") {
+		t.Fatalf("got %v want out of date error", err)
+	}
+}
+
+func TestCheckQueries(t *testing.T) {
+	dir := t.TempDir()
+	for _, name := range []string{"schema.sql", "people.sql", "queries.go", "queries_dbx.go"} {
+		src, err := os.ReadFile(filepath.Join("internal/queries", name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		if name == "people.sql" {
+			// a new column changes the row type
+			src = []byte(strings.Replace(string(src), "SELECT id, first_name, last_name, email", "SELECT id, first_name, last_name, email, added_at", 1))
+		}
+		if err := os.WriteFile(filepath.Join(dir, name), src, 0o644); err != nil {
+			t.Fatal(err)
+		}
+	}
+	args := []string{"-queries", filepath.Join(dir, "*.sql"), "-schema", filepath.Join(dir, "schema.sql"), "-check", dir}
+	if err := run(args, io.Discard); err == nil || !strings.Contains(err.Error(), "is out of date") {
+		t.Fatalf("got %v want out of date error", err)
+	}
+	if err := run(slices.Delete(slices.Clone(args), 4, 5), io.Discard); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if err := run(args, io.Discard); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+}
+
+func TestArgs(t *testing.T) {
+	for _, args := range [][]string{
+		{},
+		{"-type", "Person", "-queries", "*.sql", "-schema", "schema.sql"},
+		{"-queries", "*.sql"},
+		{"-type", "Person", "a", "b"},
+	} {
+		if err := run(args, io.Discard); err == nil || err.Error() != "invalid arguments" {
+			t.Errorf("got %v want invalid arguments for %q", err, args)
+		}
+	}
+}
+
+func TestGenerateErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		types string
+		want  string
+	}{
+		{name: "unknown type", types: "Nobody", want: "Nobody"},
+		{name: "not a struct", types: "Name", want: "not a struct"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := run([]string{"-type", tt.types, "-check", "internal/people"}, io.Discard)
+			if err == nil || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v want error containing %q", err, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,284 @@
+package main
+
+import (
+	"fmt"
+	"go/token"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+	"unicode"
+)
+
+// goType is the Go type of a column or parameter.
+type goType struct {
+	name string // e.g. int64, sql.NullString or []int64
+}
+
+// slice returns the slice type of t.
+func (t goType) slice() goType {
+	return goType{name: "[]" + t.name}
+}
+
+// importPath returns the import path of the package of t, if any.
+func (t goType) importPath() string {
+	name := strings.TrimLeft(t.name, "[]")
+	switch {
+	case strings.HasPrefix(name, "sql."):
+		return "database/sql"
+	case strings.HasPrefix(name, "time."):
+		return "time"
+	}
+	return ""
+}
+
+// nullTypes are the types used for nullable columns.
+var nullTypes = map[string]string{
+	"int64":     "sql.NullInt64",
+	"float64":   "sql.NullFloat64",
+	"bool":      "sql.NullBool",
+	"string":    "sql.NullString",
+	"time.Time": "sql.NullTime",
+	"[]byte":    "[]byte", // nil for NULL
+}
+
+// goTypeOf returns the Go type of c, using the sql.Null types if nullable
+// is true.
+func goTypeOf(c *column, nullable bool) (goType, error) {
+	typ, _, _ := strings.Cut(c.typ, "(")
+	var name string
+	switch typ {
+	case "bool", "boolean":
+		name = "bool"
+	case "tinyint":
+		name = "int64"
+		if c.typ == "tinyint(1)" { // the MySQL BOOLEAN
+			name = "bool"
+		}
+	case "smallint", "mediumint", "int", "integer", "bigint", "int2", "int4", "int8",
+		"serial", "smallserial", "bigserial", "serial4", "serial8", "year":
+		name = "int64"
+	case "float", "double", "real", "decimal", "numeric", "float4", "float8":
+		name = "float64"
+	case "char", "varchar", "character", "nchar", "nvarchar", "tinytext", "text", "mediumtext",
+		"longtext", "enum", "set", "json", "jsonb", "uuid", "citext", "time":
+		name = "string"
+	case "binary", "varbinary", "tinyblob", "blob", "mediumblob", "longblob", "bytea":
+		name = "[]byte"
+	case "date", "datetime", "timestamp", "timestamptz":
+		name = "time.Time"
+	default:
+		return goType{}, fmt.Errorf("column %s has unsupported type %s", c.name, c.typ)
+	}
+	if nullable {
+		name = nullTypes[name]
+	}
+	return goType{name: name}, nil
+}
+
+// commonInitialisms are written in upper case in Go names.
+var commonInitialisms = map[string]bool{
+	"api": true, "html": true, "http": true, "id": true, "ip": true, "json": true,
+	"sql": true, "uri": true, "url": true, "uuid": true,
+}
+
+// goName returns the exported Go name of a column or parameter, e.g.
+// UserID for user_id.
+func goName(name string) string {
+	var b strings.Builder
+	for _, part := range strings.FieldsFunc(name, func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	}) {
+		if commonInitialisms[strings.ToLower(part)] {
+			b.WriteString(strings.ToUpper(part))
+			continue
+		}
+		b.WriteString(strings.ToUpper(part[:1]) + part[1:])
+	}
+	if b.Len() == 0 || !unicode.IsLetter(rune(b.String()[0])) {
+		return "X" + b.String()
+	}
+	return b.String()
+}
+
+// generateQueries returns the source of the functions of the queries in
+// the files that match pattern, which are checked against the tables of
+// schemaFile.
+func generateQueries(pkgName, schemaFile, pattern string) ([]byte, error) {
+	src, err := os.ReadFile(schemaFile)
+	if err != nil {
+		return nil, err
+	}
+	s, err := parseSchema(string(src))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %w", schemaFile, err)
+	}
+	files, err := filepath.Glob(pattern)
+	if err != nil {
+		return nil, err
+	}
+	// the schema may be in the same directory as the queries
+	files = slices.DeleteFunc(files, func(f string) bool { return filepath.Clean(f) == filepath.Clean(schemaFile) })
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match %s", pattern)
+	}
+
+	g := &generator{name: pkgName, imports: map[string]string{}}
+	seen := map[string]*query{}
+	for _, file := range files {
+		src, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		queries, err := parseQueries(file, string(src))
+		if err != nil {
+			return nil, err
+		}
+		for _, q := range queries {
+			if !token.IsIdentifier(q.name) || !token.IsExported(q.name) {
+				return nil, fmt.Errorf("%s:%d: query name %s is not an exported Go identifier", q.file, q.line-1, q.name)
+			}
+			if prev, ok := seen[q.name]; ok {
+				return nil, fmt.Errorf("%s:%d: query %s is already defined at %s:%d", q.file, q.line-1, q.name, prev.file, prev.line-1)
+			}
+			seen[q.name] = q
+			a, err := analyze(q, s)
+			if err != nil {
+				return nil, err
+			}
+			if err := g.query(a); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return g.source()
+}
+
+// query writes the function of a, together with its query constant and
+// its row and parameter types.
+func (g *generator) query(a *analyzed) error {
+	g.imports["context"] = "context"
+	g.imports["github.com/Jimeux/dbx"] = "dbx"
+	name := a.name
+	constName := strings.ToLower(name[:1]) + name[1:] + "Query"
+
+	g.printf("\nconst %s = %s\n", constName, quoteSQL(a.sql))
+
+	// the row type, or the type of the only column
+	var rowType string
+	switch {
+	case a.cmd == "exec":
+	case len(a.columns) == 1:
+		rowType = g.use(a.columns[0].typ)
+	default:
+		rowType = name + "Row"
+		fields := make([]namedField, len(a.columns))
+		for i, c := range a.columns {
+			fields[i] = namedField{goName: goName(c.name), column: c.name, typ: g.use(c.typ)}
+		}
+		if err := g.structType(a, rowType, fmt.Sprintf("is a row of %s.", name), fields, true); err != nil {
+			return err
+		}
+		g.flatScanColumns(rowType, fields)
+	}
+
+	// the parameter type
+	var params, args string
+	if len(a.params) > 0 {
+		paramType := name + "Params"
+		fields := make([]namedField, len(a.params))
+		for i, p := range a.params {
+			fields[i] = namedField{goName: goName(p.name), typ: g.use(p.typ)}
+		}
+		if err := g.structType(a, paramType, fmt.Sprintf("are the parameters of %s.", name), fields, false); err != nil {
+			return err
+		}
+		params = ", arg " + paramType
+		for _, p := range a.args {
+			args += ", arg." + goName(p.name)
+		}
+	}
+
+	g.printf("\n")
+	if len(a.doc) > 0 {
+		for _, line := range a.doc {
+			g.printf("// %s\n", line)
+		}
+	} else {
+		g.printf("// %s runs the query %s of %s.\n", name, name, filepath.Base(a.file))
+	}
+	switch a.cmd {
+	case "many":
+		g.printf("func %s(ctx context.Context, q dbx.Queryer%s) dbx.Scanner[%s] {\n", name, params, rowType)
+		g.printf("return dbx.Select[%s](ctx, q, %s%s)\n}\n", rowType, constName, args)
+	case "one":
+		g.printf("func %s(ctx context.Context, q dbx.Queryer%s) (%s, error) {\n", name, params, rowType)
+		g.printf("return dbx.Get[%s](ctx, q, %s%s)\n}\n", rowType, constName, args)
+	case "exec":
+		g.imports["database/sql"] = "sql"
+		g.printf("func %s(ctx context.Context, e dbx.Execer%s) (sql.Result, error) {\n", name, params)
+		g.printf("return dbx.Exec(ctx, e, %s%s)\n}\n", constName, args)
+	}
+	return nil
+}
+
+// use records the import of the package of t and returns its name.
+func (g *generator) use(t goType) string {
+	if path := t.importPath(); path != "" {
+		g.imports[path] = filepath.Base(path)
+	}
+	return t.name
+}
+
+// namedField is a field of a generated struct type.
+type namedField struct {
+	goName string
+	column string // the db tag, if any
+	typ    string
+}
+
+// structType writes a struct type with the given fields.
+func (g *generator) structType(a *analyzed, name, doc string, fields []namedField, tags bool) error {
+	seen := map[string]bool{}
+	g.printf("\n// %s %s\n", name, doc)
+	g.printf("type %s struct {\n", name)
+	for _, f := range fields {
+		if seen[f.goName] {
+			return fmt.Errorf("%s:%d: query %s: %s has two fields named %s", a.file, a.line, a.name, name, f.goName)
+		}
+		seen[f.goName] = true
+		if tags {
+			g.printf("%s %s `db:%q`\n", f.goName, f.typ, f.column)
+		} else {
+			g.printf("%s %s\n", f.goName, f.typ)
+		}
+	}
+	g.printf("}\n")
+	return nil
+}
+
+// flatScanColumns writes the ScanColumns method of a generated row type.
+func (g *generator) flatScanColumns(name string, fields []namedField) {
+	g.imports["fmt"] = "fmt"
+	g.printf("\n// ScanColumns implements dbx.ColumnScanner.\n")
+	g.printf("func (v *%s) ScanColumns(columns []string) ([]any, error) {\n", name)
+	g.printf("dest := make([]any, len(columns))\n")
+	g.printf("for i, column := range columns {\n")
+	g.printf("switch column {\n")
+	for _, f := range fields {
+		g.printf("case %q:\ndest[i] = &v.%s\n", f.column, f.goName)
+	}
+	g.printf("default:\n")
+	g.printf("return nil, fmt.Errorf(\"missing destination name %%s in %%T\", column, v)\n")
+	g.printf("}\n}\n")
+	g.printf("return dest, nil\n}\n")
+}
+
+// quoteSQL returns query as a Go string literal, using a raw string unless
+// it contains a backquote.
+func quoteSQL(query string) string {
+	if strings.Contains(query, "`") || strings.Contains(query, "\r") {
+		return fmt.Sprintf("%q", query)
+	}
+	return "`" + query + "`"
+}
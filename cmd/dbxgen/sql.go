@@ -0,0 +1,375 @@
+package main
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// tokenKind is the kind of a SQL token.
+type tokenKind int
+
+const (
+	tokIdent  tokenKind = iota // identifier or keyword
+	tokNumber                  // numeric literal
+	tokString                  // string literal
+	tokParam                   // :name parameter
+	tokPunct                   // operator or punctuation
+)
+
+// sqlToken is a SQL token. Comments and whitespace are not tokens.
+type sqlToken struct {
+	kind   tokenKind
+	text   string // unquoted for identifiers, without : for parameters
+	quoted bool   // true for quoted identifiers, which are never keywords
+	pos    int    // byte offsets of the token in the source
+	end    int
+}
+
+// is reports whether t is one of the given keywords.
+func (t sqlToken) is(keywords ...string) bool {
+	if t.kind != tokIdent || t.quoted {
+		return false
+	}
+	for _, k := range keywords {
+		if strings.EqualFold(t.text, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// isPunct reports whether t is one of the given operators.
+func (t sqlToken) isPunct(ops ...string) bool {
+	return t.kind == tokPunct && slices.Contains(ops, t.text)
+}
+
+// lex splits src into tokens. Quoted identifiers use backticks or double
+// quotes, and comments start with --, # or /*.
+func lex(src string) ([]sqlToken, error) {
+	var toks []sqlToken
+	for i := 0; i < len(src); {
+		c := src[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+			continue
+		case c == '#' || c == '-' && strings.HasPrefix(src[i:], "--"):
+			if end := strings.IndexByte(src[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(src)
+			}
+			continue
+		case c == '/' && strings.HasPrefix(src[i:], "/*"):
+			end := strings.Index(src[i+2:], "*/")
+			if end < 0 {
+				return nil, errors.New("unterminated comment")
+			}
+			i += end + 4
+			continue
+		case c == '\'' || c == '"' || c == '`':
+			end, ok := quoteEnd(src, i)
+			if !ok {
+				return nil, fmt.Errorf("unterminated quote %c", c)
+			}
+			if c == '\'' {
+				toks = append(toks, sqlToken{kind: tokString, text: src[i:end], pos: i, end: end})
+			} else {
+				q := string(c)
+				name := strings.ReplaceAll(src[i+1:end-1], q+q, q)
+				toks = append(toks, sqlToken{kind: tokIdent, text: name, quoted: true, pos: i, end: end})
+			}
+			i = end
+			continue
+		case isIdentStart(c):
+			for i < len(src) && (isIdentStart(src[i]) || isDigit(src[i]) || src[i] == '$') {
+				i++
+			}
+			toks = append(toks, sqlToken{kind: tokIdent, text: src[start:i], pos: start, end: i})
+			continue
+		case isDigit(c):
+			for i < len(src) && (isDigit(src[i]) || src[i] == '.' || isIdentStart(src[i])) {
+				i++
+			}
+			toks = append(toks, sqlToken{kind: tokNumber, text: src[start:i], pos: start, end: i})
+			continue
+		case c == ':' && i+1 < len(src) && isIdentStart(src[i+1]):
+			i++
+			for i < len(src) && (isIdentStart(src[i]) || isDigit(src[i])) {
+				i++
+			}
+			if i < len(src) && src[i] == '.' {
+				return nil, fmt.Errorf("parameter %s: nested parameter names are not supported", src[start:i])
+			}
+			toks = append(toks, sqlToken{kind: tokParam, text: src[start+1 : i], pos: start, end: i})
+			continue
+		}
+		i++
+		for _, op := range []string{"<=", ">=", "<>", "!=", "::", "||"} {
+			if strings.HasPrefix(src[start:], op) {
+				i = start + len(op)
+				break
+			}
+		}
+		toks = append(toks, sqlToken{kind: tokPunct, text: src[start:i], pos: start, end: i})
+	}
+	return toks, nil
+}
+
+// quoteEnd returns the offset after the quote that closes the one at i.
+// Quotes are escaped by doubling them, or with a backslash in strings.
+func quoteEnd(src string, i int) (int, bool) {
+	q := src[i]
+	for j := i + 1; j < len(src); j++ {
+		switch {
+		case src[j] == '\\' && q == '\'':
+			j++
+		case src[j] == q && j+1 < len(src) && src[j+1] == q:
+			j++
+		case src[j] == q:
+			return j + 1, true
+		}
+	}
+	return 0, false
+}
+
+func isIdentStart(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// splitTop splits toks at the top-level occurrences of sep, i.e. those
+// outside parentheses.
+func splitTop(toks []sqlToken, sep string) [][]sqlToken {
+	var parts [][]sqlToken
+	depth, start := 0, 0
+	for i, t := range toks {
+		switch {
+		case t.isPunct("("):
+			depth++
+		case t.isPunct(")"):
+			depth--
+		case t.isPunct(sep) && depth == 0:
+			parts = append(parts, toks[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, toks[start:])
+}
+
+// table is a table of the schema.
+type table struct {
+	name    string
+	columns []*column
+}
+
+// column returns the column of t with the given name, ignoring case.
+func (t *table) column(name string) *column {
+	for _, c := range t.columns {
+		if strings.EqualFold(c.name, name) {
+			return c
+		}
+	}
+	return nil
+}
+
+// column is a column of a table.
+type column struct {
+	name    string
+	typ     string // the lowercased SQL type with its arguments, e.g. tinyint(1)
+	notNull bool
+}
+
+// schema is the set of tables of a schema, by lowercased name.
+type schema map[string]*table
+
+// parseSchema returns the tables created by the CREATE TABLE statements of
+// src. Other statements are ignored.
+func parseSchema(src string) (schema, error) {
+	toks, err := lex(src)
+	if err != nil {
+		return nil, err
+	}
+	s := schema{}
+	for _, stmt := range splitTop(toks, ";") {
+		t, err := parseCreateTable(stmt)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineOf(src, stmt[0].pos), err)
+		}
+		if t == nil {
+			continue
+		}
+		key := strings.ToLower(t.name)
+		if _, ok := s[key]; ok {
+			return nil, fmt.Errorf("line %d: table %s is created twice", lineOf(src, stmt[0].pos), t.name)
+		}
+		s[key] = t
+	}
+	return s, nil
+}
+
+// constraintKeywords start the definitions of a CREATE TABLE statement that
+// are not columns.
+var constraintKeywords = []string{"primary", "key", "index", "unique", "constraint", "foreign", "check", "fulltext", "spatial", "exclude", "like"}
+
+// parseCreateTable returns the table created by stmt, or nil if stmt is not
+// a CREATE TABLE statement.
+func parseCreateTable(stmt []sqlToken) (*table, error) {
+	if len(stmt) < 2 || !stmt[0].is("create") {
+		return nil, nil
+	}
+	i := 1
+	for i < len(stmt) && stmt[i].is("temporary", "temp", "unlogged") {
+		i++
+	}
+	if i == len(stmt) || !stmt[i].is("table") {
+		return nil, nil
+	}
+	i++
+	if i+2 < len(stmt) && stmt[i].is("if") && stmt[i+1].is("not") && stmt[i+2].is("exists") {
+		i += 3
+	}
+	name, i := qualifiedName(stmt, i)
+	if name == "" || i == len(stmt) || !stmt[i].isPunct("(") {
+		// e.g. CREATE TABLE ... AS SELECT, whose columns are unknown
+		return nil, errors.New("unsupported CREATE TABLE statement")
+	}
+	// table options after the closing parenthesis are ignored
+	depth, end := 0, i
+	for ; end < len(stmt); end++ {
+		if stmt[end].isPunct("(") {
+			depth++
+		} else if stmt[end].isPunct(")") {
+			if depth--; depth == 0 {
+				break
+			}
+		}
+	}
+
+	t := &table{name: name}
+	for _, def := range splitTop(stmt[i+1:end], ",") {
+		if len(def) == 0 || def[0].is(constraintKeywords...) {
+			continue
+		}
+		if len(def) < 2 || def[0].kind != tokIdent || def[1].kind != tokIdent {
+			return nil, fmt.Errorf("table %s: invalid column definition", name)
+		}
+		c := &column{name: def[0].text, typ: strings.ToLower(def[1].text)}
+		rest := def[2:]
+		if len(rest) > 0 && rest[0].isPunct("(") {
+			j := slices.IndexFunc(rest, func(t sqlToken) bool { return t.isPunct(")") })
+			if j < 0 {
+				return nil, fmt.Errorf("table %s: invalid type of column %s", name, c.name)
+			}
+			var args []string
+			for _, a := range rest[1:j] {
+				args = append(args, a.text)
+			}
+			c.typ += "(" + strings.Join(args, "") + ")"
+			rest = rest[j+1:]
+		}
+		for j, tok := range rest {
+			switch {
+			case tok.is("not") && j+1 < len(rest) && rest[j+1].is("null"),
+				tok.is("primary") && j+1 < len(rest) && rest[j+1].is("key"):
+				c.notNull = true
+			}
+		}
+		if strings.HasSuffix(c.typ, "serial") {
+			c.notNull = true
+		}
+		if t.column(c.name) != nil {
+			return nil, fmt.Errorf("table %s: column %s is defined twice", name, c.name)
+		}
+		t.columns = append(t.columns, c)
+	}
+	return t, nil
+}
+
+// qualifiedName returns the name at toks[i], without its schema if it is
+// qualified, and the index of the next token.
+func qualifiedName(toks []sqlToken, i int) (string, int) {
+	if i >= len(toks) || toks[i].kind != tokIdent {
+		return "", i
+	}
+	name := toks[i].text
+	i++
+	for i+1 < len(toks) && toks[i].isPunct(".") && toks[i+1].kind == tokIdent {
+		name = toks[i+1].text
+		i += 2
+	}
+	return name, i
+}
+
+// lineOf returns the line number of the byte offset pos of src.
+func lineOf(src string, pos int) int {
+	return strings.Count(src[:pos], "\n") + 1
+}
+
+// query is a named query of a .sql file.
+type query struct {
+	name string
+	cmd  string // one, many or exec
+	doc  []string
+	src  string // the SQL of the query
+	file string
+	line int // the line of src in file
+}
+
+// nameComment is the comment that starts a query, e.g.
+// -- name: ListPeople :many
+var nameComment = regexp.MustCompile(`^--\s*name:\s*(\S+)\s+:(\w+)\s*$`)
+
+// parseQueries returns the queries of the .sql file name with contents src.
+// Every query starts with a name comment, and may be followed by more
+// comments, which are used as the documentation of the generated function.
+func parseQueries(name, src string) ([]*query, error) {
+	var queries []*query
+	var q *query
+	var body []string
+	flush := func() {
+		if q != nil {
+			q.src = strings.TrimSuffix(strings.TrimSpace(strings.Join(body, "\n")), ";")
+			queries = append(queries, q)
+		}
+	}
+	for i, line := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if m := nameComment.FindStringSubmatch(trimmed); m != nil {
+			flush()
+			q, body = &query{name: m[1], cmd: m[2], file: name, line: i + 2}, nil
+			switch q.cmd {
+			case "one", "many", "exec":
+			default:
+				return nil, fmt.Errorf("%s:%d: query %s: unknown command :%s; use :one, :many or :exec", name, i+1, q.name, q.cmd)
+			}
+			continue
+		}
+		switch {
+		case q == nil && trimmed != "" && !strings.HasPrefix(trimmed, "--"):
+			return nil, fmt.Errorf("%s:%d: query without a name comment", name, i+1)
+		case q == nil:
+		case len(body) == 0 && strings.HasPrefix(trimmed, "--"):
+			q.doc = append(q.doc, strings.TrimSpace(strings.TrimPrefix(trimmed, "--")))
+			q.line++
+		case len(body) == 0 && trimmed == "":
+			q.line++
+		default:
+			body = append(body, line)
+		}
+	}
+	flush()
+	for _, q := range queries {
+		if q.src == "" {
+			return nil, fmt.Errorf("%s:%d: query %s is empty", q.file, q.line-1, q.name)
+		}
+	}
+	return queries, nil
+}
@@ -0,0 +1,17 @@
+// Command dbxvet checks the columns of constant dbx.Select and dbx.Get
+// queries against their type arguments. See package dbxvet for the checks.
+//
+// It is run by go vet:
+//
+//	go install github.com/Jimeux/dbx/cmd/dbxvet
+//	go vet -vettool=$(which dbxvet) ./...
+package main
+
+import (
+	"github.com/Jimeux/dbx/dbxvet"
+	"golang.org/x/tools/go/analysis/singlechecker"
+)
+
+func main() {
+	singlechecker.Main(dbxvet.Analyzer)
+}
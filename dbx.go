@@ -3,20 +3,110 @@ package dbx
 import (
 	"context"
 	"database/sql"
-	"errors"
+	"fmt"
 	"iter"
 	"strings"
 )
 
 var DefaultMapper = NewMapperFunc("db", strings.ToLower)
 
+// DB wraps a *sql.DB with the settings used by the generic helpers in this
+// package. Create one with NewDB or Open; the zero value is not usable.
 type DB struct {
 	*sql.DB
-	mapper *Mapper
-	// TODO @Jimeux want to focus on type-safety, so no need to support this?
+	mapper      *Mapper
+	dialect     Dialect
 	isUnsafe    bool // true allows silently ignoring SQL columns that are missing in struct fields
-	noRowsErr   bool
+	noRowsErr   bool // true makes Get return sql.ErrNoRows when the query has no results
+	errorArgs   bool // true includes args in QueryErrors
 	errHandlers []func(error) error
+	classifiers []Classifier // used before defaultClassifiers
+	retry       RetryPolicy  // used by WithTx
+	stmts       *stmtCache   // nil unless WithStmtCache is used
+	hooks       []Hook
+}
+
+// handleErr classifies err and passes it through the registered error
+// handlers in order. It is safe to call on a nil *DB, in which case err is
+// returned as is.
+func (db *DB) handleErr(err error) error {
+	if db == nil || err == nil {
+		return err
+	}
+	return db.runHandlers(db.classify(err))
+}
+
+// runHandlers passes err through the registered error handlers in order.
+// It is safe to call on a nil *DB.
+func (db *DB) runHandlers(err error) error {
+	if db == nil {
+		return err
+	}
+	for _, h := range db.errHandlers {
+		err = h(err)
+	}
+	return err
+}
+
+// mapperOrDefault returns the Mapper of db, or DefaultMapper if db is nil.
+func (db *DB) mapperOrDefault() *Mapper {
+	if db == nil {
+		return DefaultMapper
+	}
+	return db.mapper
+}
+
+// dialectOrDefault returns the Dialect of db, or MySQL if db is nil.
+func (db *DB) dialectOrDefault() Dialect {
+	if db == nil {
+		return MySQL
+	}
+	return db.dialect
+}
+
+// Dialect returns the Dialect of db.
+func (db *DB) Dialect() Dialect {
+	return db.dialect
+}
+
+// Rebind rewrites the ? placeholders in query for the Dialect of db.
+func (db *DB) Rebind(query string) string {
+	return Rebind(db.dialect, query)
+}
+
+// bind prepares query and args to be sent to the database: if expand is
+// true and query has ? placeholders, slice args are expanded with In, and
+// placeholders are rewritten for the Dialect of db. Queries without ?
+// placeholders, e.g. with the $1 placeholders of Postgres, are sent as is,
+// so that slices can be passed to drivers that support arrays. Statements
+// built by this package don't expand args, as their slice values are
+// column values. bind is safe to call on a nil *DB, in which case
+// placeholders are unchanged.
+func (db *DB) bind(query string, args []any, expand bool) (string, []any, error) {
+	q := quotingOf(db.dialectOrDefault())
+	if expand && hasPlaceholder(q, query) {
+		var err error
+		if query, args, err = in(q, query, args); err != nil {
+			return "", nil, err
+		}
+	}
+	if db != nil {
+		query = db.Rebind(query)
+	}
+	return query, args, nil
+}
+
+// dbOf returns the *DB that configures q, or nil if q is a plain
+// database/sql type.
+func dbOf(q any) *DB {
+	switch q := q.(type) {
+	case *DB:
+		return q
+	case *Tx:
+		return q.db
+	default:
+		return nil
+	}
 }
 
 type Execer interface {
@@ -27,32 +117,145 @@ type Queryer interface {
 	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
 }
 
-// TODO 2024/02/24 @Jimeux want to prevent *sql.RawBytes in a constraint
-
+// Get returns the first row of a query as type T.
+// If the query has no results, the zero value of T is returned with a nil
+// error, unless q is a *DB configured WithNoRowsError.
 func Get[T any](ctx context.Context, q Queryer, query string, args ...any) (T, error) {
-	// TODO 2024/02/25 @Jimeux decide on nil vs. error handling
-	for row, err := range scan[T](ctx, q, query, args...) {
-		if err != nil {
-			switch q := q.(type) {
-			case *DB:
-				if !q.noRowsErr && errors.Is(err, sql.ErrNoRows) {
-					return row, nil
-				}
-			}
-		}
+	return get[T](ctx, q, query, args, true)
+}
+
+// get is Get with control over the expansion of args, as in bind.
+func get[T any](ctx context.Context, q Queryer, query string, args []any, expand bool) (T, error) {
+	var t T
+	db := dbOf(q)
+	bound, boundArgs, err := db.bind(query, args, expand)
+	if err != nil {
+		return t, db.queryErr(err, query, args, nil, -1)
+	}
+	for row, err := range scanBound[T](ctx, q, bound, boundArgs) {
 		return row, err
 	}
-	var t T
+	if db != nil && db.noRowsErr {
+		return t, db.queryErr(sql.ErrNoRows, bound, boundArgs, nil, -1)
+	}
 	return t, nil
 }
 
+// Select returns a Scanner that runs the query lazily and yields each row
+// as type T.
 func Select[T any](ctx context.Context, q Queryer, query string, args ...any) Scanner[T] {
-	return Scanner[T](scan[T](ctx, q, query, args...))
+	return Scanner[T](scan[T](ctx, q, query, args, true))
+}
+
+// ScanRaw runs a query and calls fn with the columns of each row, without
+// copying them. The slices of cols, as with sql.RawBytes, are only valid
+// until fn returns, so fn must copy any value it keeps; NULL columns are
+// nil. If fn returns an error, no more rows are read and ScanRaw returns
+// it as is.
+//
+// Get and Select don't accept sql.RawBytes, since a yielded row is still in
+// use when the next one is read.
+func ScanRaw(ctx context.Context, q Queryer, query string, fn func(cols [][]byte) error, args ...any) error {
+	db := dbOf(q)
+	bound, boundArgs, err := db.bind(query, args, true)
+	if err != nil {
+		return db.queryErr(err, query, args, nil, -1)
+	}
+	_, inTx := q.(*Tx)
+	ctx, after := db.beforeQuery(ctx, inTx, QuerySelect, bound, boundArgs)
+	scanned := 0
+	var failed error
+	defer func() { after(scanned, failed) }()
+
+	rows, err := db.queryContext(ctx, q, bound, boundArgs)
+	if err != nil {
+		failed = db.queryErr(err, bound, boundArgs, nil, -1)
+		return failed
+	}
+	defer func() { _ = rows.Close() }()
+	columns, err := rows.Columns()
+	if err != nil {
+		failed = db.queryErr(err, bound, boundArgs, nil, -1)
+		return failed
+	}
+
+	raw := make([]sql.RawBytes, len(columns))
+	values := make([]any, len(columns))
+	for i := range raw {
+		values[i] = &raw[i]
+	}
+	cols := make([][]byte, len(columns))
+	for row := 0; rows.Next(); row++ {
+		if err := rows.Scan(values...); err != nil {
+			failed = db.queryErr(fmt.Errorf("failed to scan raw values: %w", err), bound, boundArgs, columns, row)
+			return failed
+		}
+		for i, b := range raw {
+			cols[i] = b
+		}
+		scanned++
+		if err := fn(cols); err != nil {
+			failed = err
+			return err
+		}
+	}
+	if err := rows.Err(); err != nil {
+		failed = db.queryErr(err, bound, boundArgs, columns, scanned)
+		return failed
+	}
+	return nil
+}
+
+// Exec executes a query that doesn't return rows, e.g. an INSERT or UPDATE.
+// Slice arguments are expanded as described in In if query has ?
+// placeholders, and placeholders are rewritten for the Dialect of e if it
+// is a *DB.
+func Exec(ctx context.Context, e Execer, query string, args ...any) (sql.Result, error) {
+	return exec(ctx, e, query, args, true)
+}
+
+// exec is Exec with control over the expansion of args, as in bind.
+func exec(ctx context.Context, e Execer, query string, args []any, expand bool) (sql.Result, error) {
+	db := dbOf(e)
+	bound, boundArgs, err := db.bind(query, args, expand)
+	if err != nil {
+		return nil, db.queryErr(err, query, args, nil, -1)
+	}
+	_, inTx := e.(*Tx)
+	ctx, after := db.beforeQuery(ctx, inTx, QueryExec, bound, boundArgs)
+	res, err := db.execContext(ctx, e, bound, boundArgs)
+	if err != nil {
+		err = db.queryErr(err, bound, boundArgs, nil, -1)
+	}
+	after(0, err)
+	return res, err
 }
 
 // Scanner returns the row(s) of a query as type T.
 type Scanner[T any] iter.Seq2[T, error]
 
+// FromSlice returns a Scanner that yields the elements of s.
+func FromSlice[T any](s []T) Scanner[T] {
+	return func(yield func(T, error) bool) {
+		for _, v := range s {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
+// FromSeq returns a Scanner that yields the values of seq.
+func FromSeq[T any](seq iter.Seq[T]) Scanner[T] {
+	return func(yield func(T, error) bool) {
+		for v := range seq {
+			if !yield(v, nil) {
+				return
+			}
+		}
+	}
+}
+
 // Collect collects the result of the query in a slice of type []T.
 func (s Scanner[T]) Collect() ([]T, error) {
 	return s.slice(0)
@@ -0,0 +1,151 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type rawPerson struct {
+	FirstName sql.RawBytes `db:"first_name"`
+	Last      string       `db:"last"`
+}
+
+type rawNested struct {
+	Name struct {
+		First *sql.RawBytes `db:"first"`
+	} `db:"name"`
+}
+
+type rawIgnored struct {
+	FirstName string       `db:"first_name"`
+	Last      string       `db:"last"`
+	Buf       sql.RawBytes `db:"-"`
+}
+
+func TestRawBytesDestinations(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, conn := newFakeDB(t, personRows)
+	db := NewDB(sqlDB)
+	tests := []struct {
+		name string
+		run  func() error
+		want string
+	}{
+		{
+			name: "Select RawBytes",
+			run: func() error {
+				_, err := Select[sql.RawBytes](ctx, db, "SELECT first_name FROM person").Collect()
+				return err
+			},
+			want: "sql.RawBytes dest type sql.RawBytes is invalid after the next row",
+		},
+		{
+			name: "Get *RawBytes",
+			run: func() error {
+				_, err := Get[*sql.RawBytes](ctx, sqlDB, "SELECT first_name FROM person")
+				return err
+			},
+			want: "sql.RawBytes dest type *sql.RawBytes is invalid after the next row",
+		},
+		{
+			name: "RawBytes field",
+			run: func() error {
+				_, err := Get[*rawPerson](ctx, db, "SELECT * FROM person")
+				return err
+			},
+			want: "sql.RawBytes field first_name in *dbx.rawPerson is invalid after the next row",
+		},
+		{
+			name: "nested RawBytes field",
+			run: func() error {
+				_, err := Select[rawNested](ctx, db, "SELECT * FROM person").Collect()
+				return err
+			},
+			want: "sql.RawBytes field name.first in dbx.rawNested is invalid after the next row",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := tt.run()
+			var qe *QueryError
+			if !errors.As(err, &qe) || !strings.Contains(err.Error(), tt.want) {
+				t.Fatalf("got %v want a QueryError containing %q", err, tt.want)
+			}
+		})
+	}
+	if got := conn.queries(); len(got) != 0 {
+		t.Fatalf("got queries %q want none", got)
+	}
+
+	// fields that are not mapped are not scanned into
+	got, err := Select[rawIgnored](ctx, db, "SELECT * FROM person").Collect()
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	want := []rawIgnored{{FirstName: "John", Last: "Doe"}, {FirstName: "Jane", Last: "Roe"}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+}
+
+func TestScanRaw(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, conn := newFakeDB(t, func(query string, _ []driver.NamedValue) (fakeResult, error) {
+		if strings.Contains(query, "missing") {
+			return fakeResult{}, errors.New("no such table")
+		}
+		return rowsOf([]string{"first_name", "email"},
+			[]driver.Value{"John", nil},
+			[]driver.Value{"Jane", "jane@example.com"},
+			[]driver.Value{"Joe", "joe@example.com"},
+		), nil
+	})
+	db := NewDB(sqlDB, WithDialect(Postgres))
+
+	var got [][]string
+	err := ScanRaw(ctx, db, "SELECT first_name, email FROM person WHERE id IN (?)", func(cols [][]byte) error {
+		row := make([]string, len(cols))
+		for i, c := range cols {
+			if c == nil {
+				row[i] = "NULL"
+			} else {
+				row[i] = string(c)
+			}
+		}
+		got = append(got, row)
+		return nil
+	}, []int{1, 2, 3})
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	want := [][]string{{"John", "NULL"}, {"Jane", "jane@example.com"}, {"Joe", "joe@example.com"}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+	if diff := cmp.Diff(conn.queries(), []string{"SELECT first_name, email FROM person WHERE id IN ($1, $2, $3)"}); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+
+	// the error of fn is returned as is, and no more rows are read
+	errStop := errors.New("stop")
+	calls := 0
+	err = ScanRaw(ctx, sqlDB, "SELECT first_name, email FROM person", func([][]byte) error {
+		calls++
+		return errStop
+	})
+	if err != errStop || calls != 1 {
+		t.Fatalf("got %v after %d calls want %v after 1", err, calls, errStop)
+	}
+
+	var qe *QueryError
+	err = ScanRaw(ctx, db, "SELECT * FROM missing", func([][]byte) error { return nil })
+	if !errors.As(err, &qe) || qe.Row != -1 || !strings.Contains(err.Error(), "no such table") {
+		t.Fatalf("got %v want a QueryError for the missing table", err)
+	}
+}
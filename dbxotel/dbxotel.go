@@ -0,0 +1,143 @@
+// Package dbxotel traces the statements run through a dbx.DB with
+// OpenTelemetry.
+//
+//	db := dbx.NewDB(sqlDB, dbx.WithHooks(dbxotel.NewHook()))
+//
+// Each statement gets a client span with the db.system, db.statement and
+// db.operation attributes. The statement is normalized with
+// dbx.NormalizeQueryFor and the Dialect of the DB, so it doesn't contain
+// the values of literals. Spans are named by the name of the query set
+// with dbx.WithQueryName, or by the operation, e.g. SELECT.
+package dbxotel
+
+import (
+	"context"
+	"strings"
+
+	"github.com/Jimeux/dbx"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const instrumentationName = "github.com/Jimeux/dbx/dbxotel"
+
+// Option configures a Hook created by NewHook.
+type Option func(*config)
+
+type config struct {
+	provider trace.TracerProvider
+	system   string
+}
+
+// WithTracerProvider sets the TracerProvider used to create spans. The
+// global TracerProvider is used by default.
+func WithTracerProvider(tp trace.TracerProvider) Option {
+	return func(c *config) {
+		if tp != nil {
+			c.provider = tp
+		}
+	}
+}
+
+// WithDBSystem sets the db.system attribute of spans, which is otherwise
+// derived from the Dialect of the DB.
+func WithDBSystem(system string) Option {
+	return func(c *config) {
+		c.system = system
+	}
+}
+
+// Hook is a dbx.Hook that creates a span for each statement. The span
+// starts when the statement is run and ends when it completes. For Select
+// that is when iteration over the rows ends, and the number of rows yielded
+// is recorded in the db.response.returned_rows attribute.
+//
+// The context returned by BeforeQuery, which is used to run the statement,
+// contains the span, so spans created by the driver are its children.
+// Since Select runs its query lazily, the span is a child of the span in the
+// context passed to Select, but starts when iteration begins.
+type Hook struct {
+	tracer trace.Tracer
+	system string
+}
+
+var _ dbx.Hook = (*Hook)(nil)
+
+// NewHook returns a Hook configured by opts.
+func NewHook(opts ...Option) *Hook {
+	c := config{provider: otel.GetTracerProvider()}
+	for _, opt := range opts {
+		opt(&c)
+	}
+	return &Hook{
+		tracer: c.provider.Tracer(instrumentationName),
+		system: c.system,
+	}
+}
+
+// spanKey is the context key of the span started by a Hook. It includes
+// the Hook so that spans of other hooks, which may be started in between,
+// are not ended instead.
+type spanKey struct{ h *Hook }
+
+func (h *Hook) BeforeQuery(ctx context.Context, info dbx.QueryInfo) context.Context {
+	statement := dbx.NormalizeQueryFor(info.Dialect, info.Query)
+	op := operation(statement)
+	name := info.Name
+	if name == "" {
+		name = op
+	}
+	ctx, span := h.tracer.Start(ctx, name,
+		trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithTimestamp(info.Start),
+		trace.WithAttributes(
+			attribute.String("db.system", h.dbSystem(info.Dialect)),
+			attribute.String("db.statement", statement),
+			attribute.String("db.operation", op),
+		),
+	)
+	return context.WithValue(ctx, spanKey{h}, span)
+}
+
+func (h *Hook) AfterQuery(ctx context.Context, info dbx.QueryInfo, rowsScanned int, err error) {
+	span, ok := ctx.Value(spanKey{h}).(trace.Span)
+	if !ok {
+		return
+	}
+	if info.Kind == dbx.QuerySelect {
+		span.SetAttributes(attribute.Int("db.response.returned_rows", rowsScanned))
+	}
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End(trace.WithTimestamp(info.Start.Add(info.Duration)))
+}
+
+// dbSystem returns the db.system attribute for d.
+func (h *Hook) dbSystem(d dbx.Dialect) string {
+	if h.system != "" {
+		return h.system
+	}
+	switch d {
+	case nil:
+		return "other_sql"
+	case dbx.Postgres:
+		return "postgresql"
+	case dbx.SQLServer:
+		return "mssql"
+	default:
+		return d.Name()
+	}
+}
+
+// operation returns the first keyword of a normalized statement, e.g. SELECT.
+func operation(statement string) string {
+	statement = strings.TrimLeft(statement, "(")
+	if i := strings.IndexAny(statement, " ("); i >= 0 {
+		statement = statement[:i]
+	}
+	return strings.ToUpper(statement)
+}
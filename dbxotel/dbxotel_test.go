@@ -0,0 +1,154 @@
+package dbxotel
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"testing"
+
+	"github.com/Jimeux/dbx"
+	"github.com/google/go-cmp/cmp"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// fakeConnector answers every query with the rows 1 and 2, and fails
+// queries starting with "FAIL". It records the span of the context each
+// query is run with.
+type fakeConnector struct {
+	spans []trace.SpanContext
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) { return fakeConn{c}, nil }
+func (c *fakeConnector) Driver() driver.Driver                        { return nil }
+
+type fakeConn struct{ c *fakeConnector }
+
+func (c fakeConn) Prepare(string) (driver.Stmt, error) { return nil, errors.New("not supported") }
+func (c fakeConn) Close() error                        { return nil }
+func (c fakeConn) Begin() (driver.Tx, error)           { return fakeTx{}, nil }
+
+func (c fakeConn) QueryContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Rows, error) {
+	c.c.spans = append(c.c.spans, trace.SpanContextFromContext(ctx))
+	if query[:4] == "FAIL" {
+		return nil, errors.New("failed")
+	}
+	return &fakeRows{rows: []int64{1, 2}}, nil
+}
+
+func (c fakeConn) ExecContext(ctx context.Context, query string, _ []driver.NamedValue) (driver.Result, error) {
+	c.c.spans = append(c.c.spans, trace.SpanContextFromContext(ctx))
+	return driver.RowsAffected(1), nil
+}
+
+type fakeTx struct{}
+
+func (fakeTx) Commit() error   { return nil }
+func (fakeTx) Rollback() error { return nil }
+
+type fakeRows struct{ rows []int64 }
+
+func (r *fakeRows) Columns() []string { return []string{"n"} }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if len(r.rows) == 0 {
+		return io.EOF
+	}
+	dest[0], r.rows = r.rows[0], r.rows[1:]
+	return nil
+}
+
+// span is the part of a recorded span that is checked by the tests.
+type span struct {
+	Name       string
+	Attributes map[attribute.Key]string
+	Status     codes.Code
+	Parent     bool
+}
+
+func TestHook(t *testing.T) {
+	exporter := tracetest.NewInMemoryExporter()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSyncer(exporter))
+	conn := &fakeConnector{}
+	sqlDB := sql.OpenDB(conn)
+	t.Cleanup(func() { _ = sqlDB.Close() })
+	db := dbx.NewDB(sqlDB, dbx.WithDialect(dbx.Postgres), dbx.WithHooks(NewHook(WithTracerProvider(tp))))
+
+	ctx, parent := tp.Tracer("test").Start(context.Background(), "parent")
+	// the query runs lazily, after the Scanner is created
+	rows := dbx.Select[int](ctx, db, "select n from t where id in (?, ?) and name = 'x'", 1, 2)
+	for range rows {
+		break
+	}
+	// named queries name their spans
+	if _, err := dbx.Exec(dbx.WithQueryName(ctx, "ResetN"), db, "UPDATE t SET n = 1"); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if _, err := dbx.Get[int](ctx, db, "FAIL"); err == nil {
+		t.Fatal("got nil want error")
+	}
+	err := db.WithTx(ctx, nil, func(tx *dbx.Tx) error { return nil })
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	parent.End()
+
+	var got []span
+	spans := exporter.GetSpans()
+	for _, s := range spans[:len(spans)-1] {
+		attrs := map[attribute.Key]string{}
+		for _, kv := range s.Attributes {
+			attrs[kv.Key] = kv.Value.Emit()
+		}
+		if s.SpanKind != trace.SpanKindClient {
+			t.Errorf("got %s want client span", s.SpanKind)
+		}
+		got = append(got, span{
+			Name:       s.Name,
+			Attributes: attrs,
+			Status:     s.Status.Code,
+			Parent:     s.Parent.SpanID() == parent.SpanContext().SpanID(),
+		})
+	}
+	attrs := func(statement, op string, extra ...string) map[attribute.Key]string {
+		m := map[attribute.Key]string{"db.system": "postgresql", "db.statement": statement, "db.operation": op}
+		for i := 0; i < len(extra); i += 2 {
+			m[attribute.Key(extra[i])] = extra[i+1]
+		}
+		return m
+	}
+	want := []span{
+		{Name: "SELECT", Attributes: attrs("select n from t where id in (?) and name = ?", "SELECT", "db.response.returned_rows", "1"), Parent: true},
+		{Name: "ResetN", Attributes: attrs("UPDATE t SET n = ?", "UPDATE"), Parent: true},
+		{Name: "FAIL", Attributes: attrs("FAIL", "FAIL", "db.response.returned_rows", "0"), Status: codes.Error, Parent: true},
+		{Name: "BEGIN", Attributes: attrs("BEGIN", "BEGIN"), Parent: true},
+		{Name: "COMMIT", Attributes: attrs("COMMIT", "COMMIT"), Parent: true},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+	if len(spans[2].Events) != 1 || spans[2].Events[0].Name != "exception" {
+		t.Fatalf("got %+v want exception event", spans[2].Events)
+	}
+
+	// the driver runs each query with the context of its span
+	wantCtx := []trace.SpanContext{spans[0].SpanContext, spans[1].SpanContext, spans[2].SpanContext}
+	if diff := cmp.Diff(conn.spans, wantCtx); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+}
+
+func TestWithDBSystem(t *testing.T) {
+	if got := NewHook(WithDBSystem("tidb")).dbSystem(dbx.MySQL); got != "tidb" {
+		t.Fatalf("got %s want tidb", got)
+	}
+	if got := NewHook().dbSystem(dbx.MySQL); got != "mysql" {
+		t.Fatalf("got %s want mysql", got)
+	}
+}
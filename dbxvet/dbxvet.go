@@ -0,0 +1,296 @@
+// Package dbxvet defines an Analyzer that checks calls to dbx.Select and
+// dbx.Get whose query is a constant.
+//
+// The columns of the query are compared with the fields of the type
+// argument, as they are mapped by dbx.DefaultMapper: fields are named by
+// their db tag or their lowercased name, embedded structs are flattened
+// unless they are tagged, and the fields of nested structs are named by
+// their dotted path. The analyzer reports
+//
+//   - selected columns that have no field in a struct type,
+//   - expressions without an alias selected into a struct type, whose
+//     column names depend on the driver,
+//   - SELECT * and several columns selected into a non-struct type, and
+//   - type arguments that are or have a mapped field of type sql.RawBytes,
+//     whose memory is reused by the next row, to the functions that scan
+//     rows: Select, Get, SelectQuery, GetQuery and their Named variants.
+//
+// Only the column list of a query that starts with SELECT is checked, so
+// columns selected with * or t.* are not compared. Types that implement
+// dbx.ColumnScanner map columns themselves and are not checked either.
+// Column names must match field names exactly, as they do in dbx, whether
+// they are quoted or not.
+//
+// Run it with go vet:
+//
+//	go install github.com/Jimeux/dbx/cmd/dbxvet
+//	go vet -vettool=$(which dbxvet) ./...
+package dbxvet
+
+import (
+	"go/ast"
+	"go/constant"
+	"go/types"
+	"reflect"
+	"strings"
+
+	"golang.org/x/tools/go/analysis"
+	"golang.org/x/tools/go/analysis/passes/inspect"
+	"golang.org/x/tools/go/ast/inspector"
+	"golang.org/x/tools/go/types/typeutil"
+)
+
+const dbxPath = "github.com/Jimeux/dbx"
+
+// scanFuncs are the functions of dbx that scan rows into their type
+// argument.
+var scanFuncs = map[string]bool{
+	"Select":           true,
+	"Get":              true,
+	"SelectQuery":      true,
+	"GetQuery":         true,
+	"NamedSelect":      true,
+	"NamedGet":         true,
+	"NamedSelectQuery": true,
+	"NamedGetQuery":    true,
+}
+
+// Analyzer reports the calls to dbx functions described in the package
+// documentation.
+var Analyzer = &analysis.Analyzer{
+	Name:     "dbxvet",
+	Doc:      "check the columns of constant dbx.Select and dbx.Get queries against their type argument",
+	URL:      "https://pkg.go.dev/github.com/Jimeux/dbx/dbxvet",
+	Requires: []*analysis.Analyzer{inspect.Analyzer},
+	Run:      run,
+}
+
+func run(pass *analysis.Pass) (any, error) {
+	ins := pass.ResultOf[inspect.Analyzer].(*inspector.Inspector)
+	ins.Preorder([]ast.Node{(*ast.CallExpr)(nil)}, func(n ast.Node) {
+		call := n.(*ast.CallExpr)
+		fn, ok := typeutil.Callee(pass.TypesInfo, call).(*types.Func)
+		if !ok || fn.Pkg() == nil || fn.Pkg().Path() != dbxPath {
+			return
+		}
+		id := funcIdent(call.Fun)
+		if id == nil {
+			return
+		}
+		inst, ok := pass.TypesInfo.Instances[id]
+		if !ok || inst.TypeArgs.Len() == 0 || !scanFuncs[fn.Name()] {
+			return
+		}
+		typ := inst.TypeArgs.At(0)
+		if path, ok := rawBytesPath(typ, nil); ok {
+			typeName := types.TypeString(typ, qualifier(pass.Pkg))
+			if path == "" {
+				pass.Reportf(call.Fun.Pos(), "dbx.%s with %s: sql.RawBytes is reused by the next row; use []byte or dbx.ScanRaw", fn.Name(), typeName)
+			} else {
+				pass.Reportf(call.Fun.Pos(), "dbx.%s with %s: field %s is sql.RawBytes, which is reused by the next row; use []byte or dbx.ScanRaw", fn.Name(), typeName, path)
+			}
+			return
+		}
+
+		if (fn.Name() != "Select" && fn.Name() != "Get") || len(call.Args) < 3 {
+			return
+		}
+		tv, ok := pass.TypesInfo.Types[call.Args[2]]
+		if !ok || tv.Value == nil || tv.Value.Kind() != constant.String {
+			return
+		}
+		cols, ok := selectColumns(constant.StringVal(tv.Value))
+		if !ok {
+			return
+		}
+		checkColumns(pass, call.Args[2], typ, cols)
+	})
+	return nil, nil
+}
+
+// qualifier qualifies the names of types outside pkg by their package
+// names, as they are written in code.
+func qualifier(pkg *types.Package) types.Qualifier {
+	return func(p *types.Package) string {
+		if p == pkg {
+			return ""
+		}
+		return p.Name()
+	}
+}
+
+// funcIdent returns the identifier of the function called by fun, e.g.
+// Select in dbx.Select[T].
+func funcIdent(fun ast.Expr) *ast.Ident {
+	switch f := ast.Unparen(fun).(type) {
+	case *ast.IndexExpr:
+		return funcIdent(f.X)
+	case *ast.IndexListExpr:
+		return funcIdent(f.X)
+	case *ast.SelectorExpr:
+		return f.Sel
+	case *ast.Ident:
+		return f
+	}
+	return nil
+}
+
+// checkColumns reports the columns of a query that can't be scanned into
+// typ.
+func checkColumns(pass *analysis.Pass, query ast.Expr, typ types.Type, cols []selectColumn) {
+	typeName := types.TypeString(typ, qualifier(pass.Pkg))
+	base := deref(typ)
+	if _, ok := base.(*types.TypeParam); ok {
+		return // the type is only known when instantiated
+	}
+	if isScannable(base) {
+		for _, c := range cols {
+			if c.star {
+				pass.Reportf(query.Pos(), "SELECT * into non-struct type %s", typeName)
+				return
+			}
+		}
+		if len(cols) > 1 {
+			pass.Reportf(query.Pos(), "query selects %d columns into non-struct type %s", len(cols), typeName)
+		}
+		return
+	}
+	if hasMethod(base, "ScanColumns") {
+		return // a dbx.ColumnScanner
+	}
+
+	names := map[string]bool{}
+	fieldNames(base, "", names, nil)
+	for _, c := range cols {
+		switch {
+		case c.star:
+		case c.name == "":
+			pass.Reportf(query.Pos(), "expression %s has no alias, so the name of its column depends on the driver", c.expr)
+		case names[c.name]:
+		default:
+			pass.Reportf(query.Pos(), "missing destination name %s in %s", c.name, typeName)
+		}
+	}
+}
+
+// fieldNames adds the names of the fields of t under path to names, as
+// they are mapped by dbx.DefaultMapper. parents are the types of the
+// fields above t, whose fields are not mapped again.
+func fieldNames(t types.Type, path string, names map[string]bool, parents []types.Type) {
+	st, ok := deref(t).Underlying().(*types.Struct)
+	if !ok {
+		return
+	}
+	for i := range st.NumFields() {
+		f := st.Field(i)
+		if !f.Exported() && !f.Embedded() {
+			continue
+		}
+		tag, name := fieldName(f, st.Tag(i))
+		if name == "-" {
+			continue
+		}
+		fieldPath := name
+		if path != "" {
+			fieldPath = path + "." + name
+		}
+		recursive := false
+		for _, p := range parents {
+			recursive = recursive || types.Identical(p, f.Type())
+		}
+		if recursive {
+			continue
+		}
+		if f.Embedded() {
+			// embedded structs are flattened unless they are tagged
+			if tag == "" {
+				fieldPath = path
+			}
+			fieldNames(f.Type(), fieldPath, names, append(parents, f.Type()))
+			continue
+		}
+		names[fieldPath] = true
+		fieldNames(f.Type(), fieldPath, names, append(parents, f.Type()))
+	}
+}
+
+// fieldName returns the db tag of f and the name of its column.
+func fieldName(f *types.Var, tag string) (string, string) {
+	if !strings.Contains(tag, "db:") {
+		return "", strings.ToLower(f.Name())
+	}
+	dbTag := reflect.StructTag(tag).Get("db")
+	name, _, _ := strings.Cut(dbTag, ",")
+	return dbTag, name
+}
+
+// isScannable reports whether t is scanned as a single column, like
+// dbx.isScannable: it is a sql.Scanner, not a struct, or a struct without
+// mapped fields, such as time.Time.
+func isScannable(t types.Type) bool {
+	if hasMethod(t, "Scan") {
+		return true
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return true
+	}
+	for i := range st.NumFields() {
+		f := st.Field(i)
+		if _, name := fieldName(f, st.Tag(i)); (f.Exported() || f.Embedded()) && name != "-" {
+			return false
+		}
+	}
+	return true
+}
+
+// hasMethod reports whether *t has a method with the given name.
+func hasMethod(t types.Type, name string) bool {
+	obj, _, _ := types.LookupFieldOrMethod(types.NewPointer(t), true, nil, name)
+	_, ok := obj.(*types.Func)
+	return ok
+}
+
+// rawBytesPath reports whether t is sql.RawBytes, a pointer to it, or a
+// struct with a mapped field of one of those types, and returns the path of
+// the field. Unexported fields and fields tagged db:"-" are not scanned, so
+// they are skipped.
+func rawBytesPath(t types.Type, parents []types.Type) (string, bool) {
+	t = deref(t)
+	if named, ok := t.(*types.Named); ok {
+		obj := named.Obj()
+		if obj.Pkg() != nil && obj.Pkg().Path() == "database/sql" && obj.Name() == "RawBytes" {
+			return "", true
+		}
+	}
+	st, ok := t.Underlying().(*types.Struct)
+	if !ok {
+		return "", false
+	}
+	for _, p := range parents {
+		if types.Identical(p, t) {
+			return "", false
+		}
+	}
+	for i := range st.NumFields() {
+		f := st.Field(i)
+		if _, name := fieldName(f, st.Tag(i)); !f.Exported() && !f.Embedded() || name == "-" {
+			continue
+		}
+		if path, ok := rawBytesPath(f.Type(), append(parents, t)); ok {
+			if path == "" {
+				return f.Name(), true
+			}
+			return f.Name() + "." + path, true
+		}
+	}
+	return "", false
+}
+
+// deref returns the element type of t if it is a pointer.
+func deref(t types.Type) types.Type {
+	if p, ok := t.(*types.Pointer); ok {
+		return p.Elem()
+	}
+	return t
+}
@@ -0,0 +1,12 @@
+package dbxvet_test
+
+import (
+	"testing"
+
+	"github.com/Jimeux/dbx/dbxvet"
+	"golang.org/x/tools/go/analysis/analysistest"
+)
+
+func TestAnalyzer(t *testing.T) {
+	analysistest.Run(t, analysistest.TestData(), dbxvet.Analyzer, "people")
+}
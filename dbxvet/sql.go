@@ -0,0 +1,207 @@
+package dbxvet
+
+import (
+	"strings"
+)
+
+// token is a SQL token. Comments and whitespace are not tokens.
+type token struct {
+	text   string // unquoted for quoted identifiers
+	ident  bool   // an identifier or keyword
+	quoted bool   // a quoted identifier, which is never a keyword
+}
+
+// is reports whether t is one of the given keywords.
+func (t token) is(keywords ...string) bool {
+	if !t.ident || t.quoted {
+		return false
+	}
+	for _, k := range keywords {
+		if strings.EqualFold(t.text, k) {
+			return true
+		}
+	}
+	return false
+}
+
+// lex splits query into tokens. It reports false if a quote or a comment
+// is not terminated.
+func lex(query string) ([]token, bool) {
+	var toks []token
+	for i := 0; i < len(query); {
+		c := query[i]
+		start := i
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			i++
+		case c == '#' || strings.HasPrefix(query[i:], "--"):
+			if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+				i += end
+			} else {
+				i = len(query)
+			}
+		case strings.HasPrefix(query[i:], "/*"):
+			end := strings.Index(query[i+2:], "*/")
+			if end < 0 {
+				return nil, false
+			}
+			i += end + 4
+		case c == '\'' || c == '"' || c == '`':
+			end := quoteEnd(query, i)
+			if end < 0 {
+				return nil, false
+			}
+			if c == '\'' {
+				toks = append(toks, token{text: query[i:end]})
+			} else {
+				q := string(c)
+				toks = append(toks, token{text: strings.ReplaceAll(query[i+1:end-1], q+q, q), ident: true, quoted: true})
+			}
+			i = end
+		case isIdentByte(c):
+			for i < len(query) && (isIdentByte(query[i]) || query[i] == '$') {
+				i++
+			}
+			toks = append(toks, token{text: query[start:i], ident: !isDigit(c)})
+		default:
+			i++
+			toks = append(toks, token{text: query[start:i]})
+		}
+	}
+	return toks, true
+}
+
+// quoteEnd returns the offset after the quote that closes the one at i, or
+// -1. Quotes are escaped by doubling them, or with a backslash in strings.
+func quoteEnd(query string, i int) int {
+	q := query[i]
+	for j := i + 1; j < len(query); j++ {
+		switch {
+		case query[j] == '\\' && q == '\'':
+			j++
+		case query[j] == q && j+1 < len(query) && query[j+1] == q:
+			j++
+		case query[j] == q:
+			return j + 1
+		}
+	}
+	return -1
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z' || isDigit(c)
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
+
+// selectColumn is a column of the column list of a SELECT statement.
+type selectColumn struct {
+	name string // the name of the column, or "" for an expression without an alias
+	star bool   // * or t.*
+	expr string // the expression, for diagnostics
+}
+
+// selectModifiers may follow SELECT before the column list.
+var selectModifiers = []string{"all", "distinct", "distinctrow", "high_priority", "straight_join",
+	"sql_small_result", "sql_big_result", "sql_buffer_result", "sql_no_cache", "sql_calc_found_rows"}
+
+// columnListEnd are the keywords that end the column list.
+var columnListEnd = []string{"from", "into", "where", "group", "having", "window", "order", "limit", "union", "intersect", "except", "for"}
+
+// selectColumns returns the column list of query if it is a SELECT
+// statement. Other statements, such as WITH ... SELECT or INSERT ...
+// RETURNING, are not parsed.
+func selectColumns(query string) ([]selectColumn, bool) {
+	toks, ok := lex(query)
+	if !ok || len(toks) == 0 || !toks[0].is("select") {
+		return nil, false
+	}
+	i := 1
+	for i < len(toks) && toks[i].is(selectModifiers...) {
+		i++
+		// DISTINCT ON (...) in PostgreSQL
+		if toks[i-1].is("distinct") && i+1 < len(toks) && toks[i].is("on") && toks[i+1].text == "(" {
+			i = skipParens(toks, i+1)
+		}
+	}
+
+	var cols []selectColumn
+	depth, start := 0, i
+	for ; i <= len(toks); i++ {
+		end := i == len(toks) || depth == 0 && (toks[i].text == ";" || toks[i].is(columnListEnd...))
+		if end || depth == 0 && toks[i].text == "," {
+			if i == start {
+				return nil, false
+			}
+			cols = append(cols, column(toks[start:i]))
+			start = i + 1
+		}
+		if end {
+			break
+		}
+		switch toks[i].text {
+		case "(":
+			depth++
+		case ")":
+			depth--
+		}
+	}
+	return cols, true
+}
+
+// skipParens returns the index after the parenthesis that closes the one at
+// toks[i].
+func skipParens(toks []token, i int) int {
+	depth := 0
+	for ; i < len(toks); i++ {
+		switch toks[i].text {
+		case "(":
+			depth++
+		case ")":
+			if depth--; depth == 0 {
+				return i + 1
+			}
+		}
+	}
+	return i
+}
+
+// column returns the column selected by the expression toks.
+func column(toks []token) selectColumn {
+	texts := make([]string, len(toks))
+	for i, t := range toks {
+		texts[i] = t.text
+	}
+	c := selectColumn{expr: strings.Join(texts, "")}
+	last := toks[len(toks)-1]
+	n := len(toks)
+	switch {
+	case last.text == "*" && (n == 1 || toks[n-2].text == "."):
+		c.star = true
+	case n >= 4 && last.ident && toks[n-2].text == ":" && toks[n-3].text == ":":
+		// a PostgreSQL cast keeps the name of the column
+		c.name = column(toks[:n-3]).name
+	case n >= 2 && last.ident && toks[n-2].is("as"):
+		c.name = last.text
+	case n >= 2 && last.ident && !last.is("end", "null", "true", "false") &&
+		(toks[n-2].ident || toks[n-2].text == ")" || strings.HasPrefix(toks[n-2].text, "'")):
+		// an alias without AS
+		c.name = last.text
+	case isColumnRef(toks):
+		c.name = last.text
+	}
+	return c
+}
+
+// isColumnRef reports whether toks is a column name, optionally qualified,
+// e.g. p.first_name.
+func isColumnRef(toks []token) bool {
+	for i, t := range toks {
+		if i%2 == 0 && !t.ident || i%2 == 1 && t.text != "." {
+			return false
+		}
+	}
+	return len(toks)%2 == 1
+}
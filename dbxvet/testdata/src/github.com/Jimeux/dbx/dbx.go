@@ -0,0 +1,50 @@
+// Package dbx is a stub of github.com/Jimeux/dbx with the functions that
+// dbxvet checks.
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"iter"
+)
+
+type Queryer interface {
+	QueryContext(ctx context.Context, query string, args ...any) (*sql.Rows, error)
+}
+
+type Execer interface {
+	ExecContext(ctx context.Context, query string, args ...any) (sql.Result, error)
+}
+
+type Scanner[T any] iter.Seq2[T, error]
+
+type Query struct {
+	Name, SQL, File string
+}
+
+func Get[T any](ctx context.Context, q Queryer, query string, args ...any) (T, error) {
+	var t T
+	return t, nil
+}
+
+func Select[T any](ctx context.Context, q Queryer, query string, args ...any) Scanner[T] {
+	return nil
+}
+
+func SelectQuery[T any](ctx context.Context, q Queryer, query Query, args ...any) Scanner[T] {
+	return nil
+}
+
+func GetQuery[T any](ctx context.Context, q Queryer, query Query, args ...any) (T, error) {
+	var t T
+	return t, nil
+}
+
+func Insert[T any](ctx context.Context, e Execer, table string, v *T) (sql.Result, error) {
+	return nil, nil
+}
+
+func NamedGetQuery[T any](ctx context.Context, q Queryer, query Query, arg any) (T, error) {
+	var t T
+	return t, nil
+}
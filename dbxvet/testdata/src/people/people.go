@@ -0,0 +1,87 @@
+package people
+
+import (
+	"context"
+	"database/sql"
+	"time"
+
+	"github.com/Jimeux/dbx"
+)
+
+type Audit struct {
+	CreatedAt time.Time `db:"created_at"`
+}
+
+type Address struct {
+	City string
+}
+
+type Person struct {
+	Audit
+	ID        int64  `db:"id,auto"`
+	FirstName string `db:"first_name"`
+	Email     sql.NullString
+	Address   Address `db:"address"`
+	Manager   *Person `db:"manager"`
+	Ignored   string  `db:"-"`
+	note      string
+}
+
+// Generated implements dbx.ColumnScanner, so its columns are not checked.
+type Generated struct {
+	ID int64 `db:"id"`
+}
+
+func (g *Generated) ScanColumns(columns []string) ([]any, error) { return nil, nil }
+
+type rawRow struct {
+	ID   int64
+	Data struct{ Value sql.RawBytes }
+}
+
+// unmappedRaw has sql.RawBytes fields that are not scanned.
+type unmappedRaw struct {
+	ID      int64
+	Skipped sql.RawBytes `db:"-"`
+	raw     sql.RawBytes
+}
+
+const listPeople = `
+SELECT id, first_name, p.email, created_at, city AS "address.city", manager.id
+FROM person p`
+
+func queries(ctx context.Context, db *sql.DB, query string) {
+	dbx.Select[Person](ctx, db, listPeople)
+	dbx.Select[*Person](ctx, db, "SELECT DISTINCT ID, First_Name, `address.city` FROM person") // want `name ID in` `name First_Name in`
+	dbx.Select[Person](ctx, db, "SELECT id, name FROM person")                                 // want `missing destination name name in Person`
+	dbx.Get[*Person](ctx, db, "SELECT p.id, first_name AS fn FROM person p")                   // want `missing destination name fn in \*Person`
+	dbx.Get[Person](ctx, db, "SELECT ignored, note, audit, `Email` FROM person")               // want `name ignored in` `name note in` `name audit in` `name Email in`
+	dbx.Get[Person](ctx, db, "SELECT id, -- name\n 'x, FROM y' first_name FROM person")
+	dbx.Get[Person](ctx, db, "SELECT id, COUNT(*) FROM person GROUP BY id") // want `expression COUNT\(\*\) has no alias`
+	dbx.Get[Person](ctx, db, "SELECT id, COUNT(*) created_at, email::text FROM person")
+	dbx.Get[Person](ctx, db, "SELECT CASE WHEN id > 0 THEN 1 ELSE 0 END FROM person") // want `expression CASEWHENid>0THEN1ELSE0END has no alias`
+	dbx.Select[Person](ctx, db, "SELECT * FROM person")
+	dbx.Select[Person](ctx, db, "SELECT p.*, extra FROM person p") // want `missing destination name extra in Person`
+	dbx.Select[Person](ctx, db, "WITH p AS (SELECT 1 AS x) SELECT x FROM p")
+	dbx.Select[Person](ctx, db, query)
+	dbx.Select[Generated](ctx, db, "SELECT extra FROM person")
+
+	dbx.Get[int64](ctx, db, "SELECT COUNT(*) FROM person")
+	dbx.Get[time.Time](ctx, db, "SELECT created_at FROM person")
+	dbx.Get[sql.NullString](ctx, db, "SELECT email FROM person")
+	dbx.Get[int64](ctx, db, "SELECT * FROM person")                   // want `SELECT \* into non-struct type int64`
+	dbx.Select[*string](ctx, db, "SELECT id, first_name FROM person") // want `query selects 2 columns into non-struct type \*string`
+
+	dbx.Select[sql.RawBytes](ctx, db, "SELECT first_name FROM person") // want `dbx.Select with sql.RawBytes: sql.RawBytes is reused by the next row`
+	dbx.Get[*rawRow](ctx, db, "SELECT * FROM raw")                     // want `dbx.Get with \*rawRow: field Data.Value is sql.RawBytes`
+	dbx.SelectQuery[rawRow](ctx, db, dbx.Query{})                      // want `dbx.SelectQuery with rawRow: field Data.Value`
+	dbx.GetQuery[*rawRow](ctx, db, dbx.Query{})                        // want `dbx.GetQuery with \*rawRow: field Data.Value`
+	dbx.NamedGetQuery[rawRow](ctx, db, dbx.Query{}, nil)               // want `dbx.NamedGetQuery with rawRow: field Data.Value`
+	dbx.Select[[]byte](ctx, db, "SELECT data FROM raw")
+	dbx.Select[unmappedRaw](ctx, db, "SELECT id FROM raw")
+	dbx.Insert(ctx, db, "raw", &rawRow{})
+}
+
+func generic[T any](ctx context.Context, db *sql.DB) dbx.Scanner[T] {
+	return dbx.Select[T](ctx, db, "SELECT * FROM person")
+}
@@ -0,0 +1,198 @@
+package dbx
+
+import (
+	"database/sql/driver"
+	"reflect"
+	"strconv"
+	"strings"
+)
+
+// A Dialect describes how a database differs from the ? placeholders and
+// MySQL syntax used by queries written for this package.
+type Dialect interface {
+	// Name returns the name of the dialect, e.g. "mysql".
+	Name() string
+	// Placeholder returns the bind parameter for the n-th argument of a
+	// query, starting at 1.
+	Placeholder(n int) string
+	// Quote quotes an identifier such as a table or column name.
+	// Dotted identifiers are quoted part by part, e.g. schema.table.
+	Quote(ident string) string
+	// Capabilities reports the features supported by the database.
+	Capabilities() Capabilities
+}
+
+// Capabilities describes features that are not available in every database.
+type Capabilities struct {
+	// LastInsertID is true if sql.Result.LastInsertId is supported.
+	LastInsertID bool
+	// Returning is true if INSERT ... RETURNING is supported.
+	Returning bool
+	// Savepoints is true if SAVEPOINT statements are supported.
+	Savepoints bool
+	// MaxPlaceholders is the maximum number of bind parameters in one statement.
+	MaxPlaceholders int
+	// Upsert is the syntax used to insert or update a row in one statement.
+	Upsert UpsertSyntax
+	// BackslashEscapes is true if backslashes escape characters in string
+	// literals, as in MySQL unless NO_BACKSLASH_ESCAPES is set. Otherwise
+	// only doubled quotes are escapes, as in standard SQL.
+	BackslashEscapes bool
+	// DoubleQuotedStrings is true if "..." is a string literal, as in MySQL
+	// unless ANSI_QUOTES is set. Otherwise it is a quoted identifier.
+	DoubleQuotedStrings bool
+	// HashComments is true if # starts a comment that ends with the line,
+	// as in MySQL, rather than being an operator.
+	HashComments bool
+	// ReturningXmax is true if rows have the xmax system column, so that
+	// RETURNING (xmax = 0) reports whether an upserted row was inserted, as
+	// in Postgres.
+	ReturningXmax bool
+}
+
+// UpsertSyntax is the syntax of a statement that inserts a row, or updates
+// it if it already exists.
+type UpsertSyntax int
+
+const (
+	// NoUpsert means upserts are not supported.
+	NoUpsert UpsertSyntax = iota
+	// OnDuplicateKeyUpdate is the MySQL INSERT ... ON DUPLICATE KEY UPDATE syntax.
+	OnDuplicateKeyUpdate
+	// OnConflictDoUpdate is the Postgres and SQLite INSERT ... ON CONFLICT DO UPDATE syntax.
+	OnConflictDoUpdate
+)
+
+// The dialects supported by this package.
+var (
+	MySQL Dialect = &dialect{
+		name:        "mysql",
+		placeholder: questionMark,
+		quote:       "``",
+		caps:        Capabilities{LastInsertID: true, Savepoints: true, MaxPlaceholders: 65535, Upsert: OnDuplicateKeyUpdate, BackslashEscapes: true, DoubleQuotedStrings: true, HashComments: true},
+	}
+	Postgres Dialect = &dialect{
+		name:        "postgres",
+		placeholder: dollar,
+		quote:       `""`,
+		caps:        Capabilities{Returning: true, Savepoints: true, MaxPlaceholders: 65535, Upsert: OnConflictDoUpdate, ReturningXmax: true},
+	}
+	SQLite Dialect = &dialect{
+		name:        "sqlite",
+		placeholder: questionMark,
+		quote:       `""`,
+		caps:        Capabilities{LastInsertID: true, Returning: true, Savepoints: true, MaxPlaceholders: 32766, Upsert: OnConflictDoUpdate},
+	}
+	SQLServer Dialect = &dialect{
+		name:        "sqlserver",
+		placeholder: atP,
+		quote:       "[]",
+		caps:        Capabilities{Savepoints: true, MaxPlaceholders: 2100},
+	}
+	Oracle Dialect = &dialect{
+		name:        "oracle",
+		placeholder: colon,
+		quote:       `""`,
+		caps:        Capabilities{Returning: true, Savepoints: true, MaxPlaceholders: 65535},
+	}
+)
+
+// driverDialects maps driver names (as passed to sql.Open) and driver
+// package paths to dialects.
+var driverDialects = map[string]Dialect{
+	"mysql":                            MySQL,
+	"github.com/go-sql-driver/mysql":   MySQL,
+	"postgres":                         Postgres,
+	"pgx":                              Postgres,
+	"github.com/lib/pq":                Postgres,
+	"github.com/jackc/pgx/v5/stdlib":   Postgres,
+	"github.com/jackc/pgx/v4/stdlib":   Postgres,
+	"sqlite":                           SQLite,
+	"sqlite3":                          SQLite,
+	"github.com/mattn/go-sqlite3":      SQLite,
+	"modernc.org/sqlite":               SQLite,
+	"sqlserver":                        SQLServer,
+	"mssql":                            SQLServer,
+	"github.com/microsoft/go-mssqldb":  SQLServer,
+	"github.com/denisenkom/go-mssqldb": SQLServer,
+	"oracle":                           Oracle,
+	"godror":                           Oracle,
+	"oci8":                             Oracle,
+	"github.com/godror/godror":         Oracle,
+	"github.com/sijms/go-ora/v2":       Oracle,
+	"github.com/mattn/go-oci8":         Oracle,
+}
+
+// DialectFor returns the Dialect for a driver name as passed to sql.Open,
+// e.g. "mysql" or "pgx". It returns MySQL for unknown drivers.
+func DialectFor(driverName string) Dialect {
+	if d, ok := driverDialects[driverName]; ok {
+		return d
+	}
+	return MySQL
+}
+
+// dialectOf detects the Dialect from the package of a driver.
+// It returns MySQL for unknown drivers.
+func dialectOf(drv driver.Driver) Dialect {
+	if drv == nil {
+		return MySQL
+	}
+	return DialectFor(derefType(reflect.TypeOf(drv)).PkgPath())
+}
+
+// Rebind rewrites the ? placeholders in query into the placeholders of d.
+// Placeholders in quoted strings, quoted identifiers and comments are left
+// unchanged.
+func Rebind(d Dialect, query string) string {
+	if d.Placeholder(1) == "?" {
+		return query
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 4)
+	q := quotingOf(d)
+	n := 0
+	for i := 0; i < len(query); {
+		if end := skipLiteral(q, query, i); end > i {
+			b.WriteString(query[i:end])
+			i = end
+			continue
+		}
+		if c := query[i]; c == '?' {
+			n++
+			b.WriteString(d.Placeholder(n))
+		} else {
+			b.WriteByte(c)
+		}
+		i++
+	}
+	return b.String()
+}
+
+// dialect is the Dialect implementation of the built-in dialects.
+type dialect struct {
+	name        string
+	placeholder func(n int) string
+	quote       string // opening and closing quote characters
+	caps        Capabilities
+}
+
+func (d *dialect) Name() string               { return d.name }
+func (d *dialect) Placeholder(n int) string   { return d.placeholder(n) }
+func (d *dialect) Capabilities() Capabilities { return d.caps }
+
+func (d *dialect) Quote(ident string) string {
+	open, end := d.quote[:1], d.quote[1:]
+	parts := strings.Split(ident, ".")
+	for i, p := range parts {
+		// escape the closing quote by doubling it
+		parts[i] = open + strings.ReplaceAll(p, end, end+end) + end
+	}
+	return strings.Join(parts, ".")
+}
+
+func questionMark(int) string { return "?" }
+func dollar(n int) string     { return "$" + strconv.Itoa(n) }
+func atP(n int) string        { return "@p" + strconv.Itoa(n) }
+func colon(n int) string      { return ":" + strconv.Itoa(n) }
@@ -0,0 +1,159 @@
+package dbx
+
+import (
+	"context"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestRebind(t *testing.T) {
+	const query = "SELECT * FROM t WHERE a = ? AND b = '?' AND c IN (?, ?) -- ?\n AND d = ?"
+	tests := []struct {
+		dialect Dialect
+		want    string
+	}{
+		{MySQL, query},
+		{SQLite, query},
+		{Postgres, "SELECT * FROM t WHERE a = $1 AND b = '?' AND c IN ($2, $3) -- ?\n AND d = $4"},
+		{SQLServer, "SELECT * FROM t WHERE a = @p1 AND b = '?' AND c IN (@p2, @p3) -- ?\n AND d = @p4"},
+		{Oracle, "SELECT * FROM t WHERE a = :1 AND b = '?' AND c IN (:2, :3) -- ?\n AND d = :4"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.dialect.Name(), func(t *testing.T) {
+			if got := Rebind(tt.dialect, query); got != tt.want {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRebindEscapes(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		query   string
+		want    string
+	}{
+		// backslashes are not escapes in standard strings
+		{Postgres, `SELECT * FROM t WHERE p = 'C:\' AND id = ?`, `SELECT * FROM t WHERE p = 'C:\' AND id = $1`},
+		{Postgres, `SELECT * FROM t WHERE p = 'it''s ?' AND id = ?`, `SELECT * FROM t WHERE p = 'it''s ?' AND id = $1`},
+		// but they are in escape strings
+		{Postgres, `SELECT * FROM t WHERE p = E'it\'s ?' AND id = ?`, `SELECT * FROM t WHERE p = E'it\'s ?' AND id = $1`},
+		{Postgres, `SELECT * FROM t WHERE p = e'C:\\' AND id = ?`, `SELECT * FROM t WHERE p = e'C:\\' AND id = $1`},
+		// and never in quoted identifiers
+		{Postgres, `SELECT "a\" FROM t WHERE id = ?`, `SELECT "a\" FROM t WHERE id = $1`},
+		{SQLServer, `SELECT * FROM t WHERE p = 'C:\' AND id = ?`, `SELECT * FROM t WHERE p = 'C:\' AND id = @p1`},
+	}
+	for _, tt := range tests {
+		if got := Rebind(tt.dialect, tt.query); got != tt.want {
+			t.Errorf("%s: got %q want %q", tt.dialect.Name(), got, tt.want)
+		}
+	}
+
+	// MySQL strings use backslash escapes
+	query, args, err := In(`SELECT * FROM t WHERE p = 'it\'s ?' AND id IN (?)`, []int{1, 2})
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if want := `SELECT * FROM t WHERE p = 'it\'s ?' AND id IN (?, ?)`; query != want || len(args) != 2 {
+		t.Fatalf("got %q %v want %q", query, args, want)
+	}
+	// and # starts a comment
+	query, args, err = In("SELECT * FROM t WHERE id IN (?) # IN (?)\nAND a = ?", []int{1, 2}, "a")
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if want := "SELECT * FROM t WHERE id IN (?, ?) # IN (?)\nAND a = ?"; query != want || len(args) != 3 {
+		t.Fatalf("got %q %v want %q", query, args, want)
+	}
+}
+
+func TestQuote(t *testing.T) {
+	tests := []struct {
+		dialect Dialect
+		ident   string
+		want    string
+	}{
+		{MySQL, "person", "`person`"},
+		{MySQL, "dbx.person", "`dbx`.`person`"},
+		{MySQL, "we`ird", "`we``ird`"},
+		{Postgres, "public.person", `"public"."person"`},
+		{Postgres, `we"ird`, `"we""ird"`},
+		{SQLite, "person", `"person"`},
+		{SQLServer, "dbo.person", "[dbo].[person]"},
+		{SQLServer, "we]ird", "[we]]ird]"},
+		{Oracle, "PERSON", `"PERSON"`},
+	}
+	for _, tt := range tests {
+		if got := tt.dialect.Quote(tt.ident); got != tt.want {
+			t.Errorf("%s.Quote(%q): got %q want %q", tt.dialect.Name(), tt.ident, got, tt.want)
+		}
+	}
+}
+
+func TestDialectFor(t *testing.T) {
+	tests := map[string]Dialect{
+		"mysql":     MySQL,
+		"pgx":       Postgres,
+		"postgres":  Postgres,
+		"sqlite3":   SQLite,
+		"sqlserver": SQLServer,
+		"godror":    Oracle,
+		"unknown":   MySQL,
+	}
+	for name, want := range tests {
+		if got := DialectFor(name); got != want {
+			t.Errorf("DialectFor(%q): got %s want %s", name, got.Name(), want.Name())
+		}
+	}
+
+	if got := dialectOf(&mysql.MySQLDriver{}); got != MySQL {
+		t.Errorf("dialectOf(*mysql.MySQLDriver): got %s want mysql", got.Name())
+	}
+	if got := dialectOf(fakeDriver{}); got != MySQL {
+		t.Errorf("dialectOf(fakeDriver): got %s want mysql", got.Name())
+	}
+}
+
+func TestWithDialect(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, conn := newFakeDB(t, personRows)
+
+	if got := NewDB(sqlDB).Dialect(); got != MySQL {
+		t.Fatalf("got %s want default mysql", got.Name())
+	}
+	db, err := Open("mysql", "root:@tcp(localhost:1)/dbx", WithDialect(Postgres))
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	defer db.Close()
+	if got := db.Dialect(); got != Postgres {
+		t.Fatalf("got %s want postgres from option", got.Name())
+	}
+
+	db = NewDB(sqlDB, WithDialect(Postgres), WithDialect(nil))
+	if _, err := Select[string](ctx, db, "SELECT first_name FROM person WHERE id IN (?) AND a = ?", []int{1, 2}, "a").Collect(); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if _, err := NamedGet[string](ctx, db, "SELECT first_name FROM person WHERE a = :a", map[string]any{"a": 1}); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if _, err := Exec(ctx, db, "DELETE FROM person WHERE id = ?", 1); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	// plain database/sql types are not rebound
+	if _, err := Exec(ctx, sqlDB, "DELETE FROM person WHERE id = ?", 1); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+
+	want := []string{
+		"SELECT first_name FROM person WHERE id IN ($1, $2) AND a = $3",
+		"SELECT first_name FROM person WHERE a = $1",
+		"DELETE FROM person WHERE id = $1",
+		"DELETE FROM person WHERE id = ?",
+	}
+	if got := conn.queries(); !cmp.Equal(got, want) {
+		t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+	}
+}
@@ -0,0 +1,243 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+
+	"github.com/go-sql-driver/mysql"
+)
+
+// Classes of driver errors. Errors returned by a DB are classified with its
+// Classifiers, so that they match these with errors.Is while still wrapping
+// the original driver error.
+var (
+	ErrDuplicateKey        = errors.New("duplicate key")
+	ErrForeignKeyViolation = errors.New("foreign key violation")
+	ErrDeadlock            = errors.New("deadlock")
+	ErrLockTimeout         = errors.New("lock wait timeout")
+	ErrConnection          = errors.New("connection error")
+)
+
+// IsDuplicateKey reports whether err is a unique or primary key violation.
+func IsDuplicateKey(err error) bool { return isClass(err, ErrDuplicateKey) }
+
+// IsForeignKeyViolation reports whether err is a foreign key constraint violation.
+func IsForeignKeyViolation(err error) bool { return isClass(err, ErrForeignKeyViolation) }
+
+// IsDeadlock reports whether err is a deadlock.
+func IsDeadlock(err error) bool { return isClass(err, ErrDeadlock) }
+
+// IsLockTimeout reports whether err is a lock wait timeout.
+func IsLockTimeout(err error) bool { return isClass(err, ErrLockTimeout) }
+
+// IsConnectionError reports whether err is caused by a broken or
+// unavailable connection.
+func IsConnectionError(err error) bool { return isClass(err, ErrConnection) }
+
+// isClass reports whether err matches class, either because it was already
+// classified by a DB or because one of the default Classifiers classifies it.
+func isClass(err error, class error) bool {
+	if err == nil {
+		return false
+	}
+	if errors.Is(err, class) {
+		return true
+	}
+	for _, c := range defaultClassifiers {
+		if c.Classify(err) == class {
+			return true
+		}
+	}
+	return false
+}
+
+// A Classifier maps driver errors to the error classes of this package,
+// e.g. ErrDuplicateKey. Use WithClassifier to add one for a driver that is
+// not supported by default.
+type Classifier interface {
+	// Classify returns the class of err, or nil if err is not classified.
+	Classify(err error) error
+}
+
+// ClassifierFunc is a function that implements Classifier.
+type ClassifierFunc func(err error) error
+
+// Classify returns f(err).
+func (f ClassifierFunc) Classify(err error) error {
+	return f(err)
+}
+
+// The Classifiers used by every DB, after any added with WithClassifier.
+var (
+	// MySQLClassifier classifies *mysql.MySQLError by error number.
+	MySQLClassifier Classifier = ClassifierFunc(classifyMySQL)
+	// SQLStateClassifier classifies errors that have a SQLState() string
+	// method, such as the errors of the Postgres drivers, by SQLSTATE code.
+	SQLStateClassifier Classifier = ClassifierFunc(classifySQLState)
+	// ConnectionClassifier classifies database/sql connection errors and
+	// network errors as ErrConnection. Context cancellations and deadlines
+	// are not connection errors.
+	ConnectionClassifier Classifier = ClassifierFunc(classifyConnection)
+)
+
+var defaultClassifiers = []Classifier{MySQLClassifier, SQLStateClassifier, ConnectionClassifier}
+
+// WithClassifier adds c to the Classifiers of the DB. It is used before the
+// default Classifiers, and before Classifiers added earlier.
+func WithClassifier(c Classifier) Option {
+	return func(db *DB) {
+		if c != nil {
+			db.classifiers = append([]Classifier{c}, db.classifiers...)
+		}
+	}
+}
+
+// classifiedError is a driver error together with its class.
+type classifiedError struct {
+	class error
+	err   error
+}
+
+func (e *classifiedError) Error() string   { return e.err.Error() }
+func (e *classifiedError) Unwrap() []error { return []error{e.class, e.err} }
+
+// classify wraps err with its class if any Classifier of db classifies it.
+// Errors that are already classified are returned unchanged.
+func (db *DB) classify(err error) error {
+	var ce *classifiedError
+	if errors.As(err, &ce) {
+		return err
+	}
+	for _, cs := range [][]Classifier{db.classifiers, defaultClassifiers} {
+		for _, c := range cs {
+			if class := c.Classify(err); class != nil {
+				return &classifiedError{class: class, err: err}
+			}
+		}
+	}
+	return err
+}
+
+func classifyMySQL(err error) error {
+	if errors.Is(err, mysql.ErrInvalidConn) {
+		return ErrConnection
+	}
+	var myErr *mysql.MySQLError
+	if !errors.As(err, &myErr) {
+		return nil
+	}
+	switch myErr.Number {
+	case 1022, 1062, 1586: // ER_DUP_KEY, ER_DUP_ENTRY, ER_DUP_ENTRY_WITH_KEY_NAME
+		return ErrDuplicateKey
+	case 1216, 1217, 1451, 1452: // ER_NO_REFERENCED_ROW(_2), ER_ROW_IS_REFERENCED(_2)
+		return ErrForeignKeyViolation
+	case 1213: // ER_LOCK_DEADLOCK
+		return ErrDeadlock
+	case 1205: // ER_LOCK_WAIT_TIMEOUT
+		return ErrLockTimeout
+	case 1040, 1053, 2006, 2013: // ER_CON_COUNT_ERROR, ER_SERVER_SHUTDOWN, CR_SERVER_GONE_ERROR, CR_SERVER_LOST
+		return ErrConnection
+	}
+	return nil
+}
+
+func classifySQLState(err error) error {
+	var stateErr interface{ SQLState() string }
+	if !errors.As(err, &stateErr) {
+		return nil
+	}
+	switch state := stateErr.SQLState(); {
+	case state == "23505": // unique_violation
+		return ErrDuplicateKey
+	case state == "23503": // foreign_key_violation
+		return ErrForeignKeyViolation
+	case state == "40P01": // deadlock_detected
+		return ErrDeadlock
+	case state == "55P03": // lock_not_available
+		return ErrLockTimeout
+	case strings.HasPrefix(state, "08"): // connection_exception
+		return ErrConnection
+	}
+	return nil
+}
+
+func classifyConnection(err error) error {
+	// context.DeadlineExceeded implements net.Error, but timeouts and
+	// cancellations of the caller say nothing about the connection
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return nil
+	}
+	var netErr net.Error
+	if errors.Is(err, driver.ErrBadConn) || errors.Is(err, sql.ErrConnDone) || errors.As(err, &netErr) {
+		return ErrConnection
+	}
+	return nil
+}
+
+// QueryError is the error returned when a query fails to run or its rows
+// fail to scan. It wraps the underlying error, which can be checked with
+// errors.Is and errors.As as usual.
+type QueryError struct {
+	Query       string   // the query as sent to the database
+	Fingerprint string   // see FingerprintFor; computed with the Dialect of the DB
+	Args        []any    // the args of the query; nil unless WithErrorArgs is used
+	Columns     []string // the columns of the result, if the query returned rows
+	Row         int      // the index of the row that failed, or -1
+	Err         error
+}
+
+func (e *QueryError) Error() string {
+	var b strings.Builder
+	b.WriteString("query ")
+	b.WriteString(strconv.Quote(e.Query))
+	if e.Row >= 0 {
+		b.WriteString(" row ")
+		b.WriteString(strconv.Itoa(e.Row))
+	}
+	if e.Args != nil {
+		fmt.Fprintf(&b, " args %v", e.Args)
+	}
+	b.WriteString(": ")
+	b.WriteString(e.Err.Error())
+	return b.String()
+}
+
+func (e *QueryError) Unwrap() error { return e.Err }
+
+// WithErrorArgs includes the args of queries in the QueryErrors returned by
+// the DB. Args are redacted by default, as they may contain personal data or
+// secrets that should not end up in logs.
+func WithErrorArgs() Option {
+	return func(db *DB) {
+		db.errorArgs = true
+	}
+}
+
+// queryErr classifies err, wraps it in a *QueryError and passes it through
+// the error handlers of db. The QueryError itself is not classified again.
+// It is safe to call on a nil *DB.
+func (db *DB) queryErr(err error, query string, args []any, columns []string, row int) error {
+	if err == nil {
+		return nil
+	}
+	if db != nil {
+		err = db.classify(err)
+	}
+	qe := &QueryError{
+		Query:       query,
+		Fingerprint: FingerprintFor(db.dialectOrDefault(), query),
+		Columns:     columns,
+		Row:         row,
+		Err:         err,
+	}
+	if db != nil && db.errorArgs {
+		qe.Args = args
+	}
+	return db.runHandlers(qe)
+}
@@ -0,0 +1,196 @@
+package dbx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+	"testing"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+// stateError is an error with a SQLSTATE code, like the errors of the
+// Postgres drivers.
+type stateError string
+
+func (e stateError) Error() string    { return "state " + string(e) }
+func (e stateError) SQLState() string { return string(e) }
+
+func TestClassify(t *testing.T) {
+	tests := []struct {
+		name string
+		err  error
+		want error
+	}{
+		{name: "mysql duplicate", err: &mysql.MySQLError{Number: 1062}, want: ErrDuplicateKey},
+		{name: "mysql foreign key", err: &mysql.MySQLError{Number: 1452}, want: ErrForeignKeyViolation},
+		{name: "mysql deadlock", err: errDeadlock, want: ErrDeadlock},
+		{name: "mysql lock timeout", err: errLockTimeout, want: ErrLockTimeout},
+		{name: "mysql server gone", err: &mysql.MySQLError{Number: 2006}, want: ErrConnection},
+		{name: "mysql invalid conn", err: mysql.ErrInvalidConn, want: ErrConnection},
+		{name: "mysql other", err: &mysql.MySQLError{Number: 1064}},
+		{name: "wrapped", err: fmt.Errorf("insert: %w", &mysql.MySQLError{Number: 1062}), want: ErrDuplicateKey},
+		{name: "sqlstate unique", err: stateError("23505"), want: ErrDuplicateKey},
+		{name: "sqlstate foreign key", err: stateError("23503"), want: ErrForeignKeyViolation},
+		{name: "sqlstate deadlock", err: stateError("40P01"), want: ErrDeadlock},
+		{name: "sqlstate lock", err: stateError("55P03"), want: ErrLockTimeout},
+		{name: "sqlstate connection", err: stateError("08006"), want: ErrConnection},
+		{name: "bad conn", err: driver.ErrBadConn, want: ErrConnection},
+		{name: "net error", err: &net.OpError{Op: "dial", Net: "tcp", Err: errors.New("connection refused")}, want: ErrConnection},
+		{name: "deadline exceeded", err: context.DeadlineExceeded},
+		{name: "canceled", err: fmt.Errorf("query: %w", context.Canceled)},
+		{name: "other", err: errors.New("other")},
+	}
+	is := map[error]func(error) bool{
+		ErrDuplicateKey:        IsDuplicateKey,
+		ErrForeignKeyViolation: IsForeignKeyViolation,
+		ErrDeadlock:            IsDeadlock,
+		ErrLockTimeout:         IsLockTimeout,
+		ErrConnection:          IsConnectionError,
+	}
+	db := &DB{}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			for class, fn := range is {
+				want := class == tt.want
+				if got := fn(tt.err); got != want {
+					t.Errorf("%v: got %t want %t", class, got, want)
+				}
+				// classified errors match with errors.Is and keep the driver error
+				err := db.classify(tt.err)
+				if got := errors.Is(err, class); got != want {
+					t.Errorf("errors.Is(%v): got %t want %t", class, got, want)
+				}
+				if !errors.Is(err, tt.err) {
+					t.Errorf("got %+v want wrapped %+v", err, tt.err)
+				}
+			}
+		})
+	}
+}
+
+func TestWithClassifier(t *testing.T) {
+	ctx := context.Background()
+	errCustom := errors.New("unique constraint failed")
+	var handled error
+	classified := 0
+	db, _ := failingDB(t, 2, errCustom,
+		WithClassifier(ClassifierFunc(func(err error) error {
+			classified++
+			if errors.Is(err, errCustom) {
+				return ErrDuplicateKey
+			}
+			return nil
+		})),
+		WithErrorHandler(func(err error) error {
+			handled = err
+			return err
+		}),
+	)
+
+	_, err := Exec(ctx, db, "UPDATE")
+	if !IsDuplicateKey(err) || !errors.Is(err, errCustom) {
+		t.Fatalf("got %+v want duplicate key", err)
+	}
+	if !IsDuplicateKey(handled) {
+		t.Fatalf("got %+v want classified error passed to handler", handled)
+	}
+
+	// query errors are classified once, even if no classifier matches
+	classified = 0
+	if err := db.queryErr(errors.New("other"), "SELECT", nil, nil, -1); classified != 1 {
+		t.Fatalf("got %d calls for %+v want 1", classified, err)
+	}
+
+	// the default classifiers still apply after custom ones
+	db.classifiers = append(db.classifiers, ClassifierFunc(func(error) error { return nil }))
+	if err := db.handleErr(errDeadlock); !errors.Is(err, ErrDeadlock) {
+		t.Fatalf("got %+v want deadlock", err)
+	}
+	// classifying twice doesn't wrap again
+	if got := db.handleErr(err); got != err {
+		t.Fatalf("got %#v want %#v", got, err)
+	}
+}
+
+func TestQueryError(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, _ := newFakeDB(t, func(query string, _ []driver.NamedValue) (fakeResult, error) {
+		switch query {
+		case "SELECT n FROM t WHERE id IN (?, ?)":
+			return rowsOf([]string{"n"}, []driver.Value{int64(1)}, []driver.Value{"x"}), nil
+		case "INSERT INTO t VALUES (?)":
+			return fakeResult{}, &mysql.MySQLError{Number: 1062, Message: "Duplicate entry"}
+		}
+		return fakeResult{}, errors.New("unexpected query")
+	})
+
+	t.Run("scan", func(t *testing.T) {
+		db := NewDB(sqlDB)
+		var err error
+		for _, err = range Select[int](ctx, db, "SELECT n FROM t WHERE id IN (?)", []int{1, 2}) {
+			if err != nil {
+				break
+			}
+		}
+		var qe *QueryError
+		if !errors.As(err, &qe) {
+			t.Fatalf("got %+v want *QueryError", err)
+		}
+		want := &QueryError{
+			Query:       "SELECT n FROM t WHERE id IN (?, ?)",
+			Fingerprint: Fingerprint("SELECT n FROM t WHERE id IN (?)"),
+			Columns:     []string{"n"},
+			Row:         1,
+		}
+		if diff := cmp.Diff(qe, want, cmpopts.IgnoreFields(QueryError{}, "Err")); diff != "" {
+			t.Fatalf("(-got +want) %s", diff)
+		}
+		if !strings.HasPrefix(err.Error(), `query "SELECT n FROM t WHERE id IN (?, ?)" row 1: `) {
+			t.Fatalf("got %q want query and row in message", err)
+		}
+	})
+
+	t.Run("exec", func(t *testing.T) {
+		var handled error
+		db := NewDB(sqlDB, WithErrorHandler(func(err error) error {
+			handled = err
+			return err
+		}))
+		_, err := Exec(ctx, db, "INSERT INTO t VALUES (?)", "secret")
+		var qe *QueryError
+		if !errors.As(err, &qe) || handled != err {
+			t.Fatalf("got %+v want *QueryError passed to handler", err)
+		}
+		if !IsDuplicateKey(err) || !errors.Is(err, ErrDuplicateKey) {
+			t.Fatalf("got %+v want duplicate key", err)
+		}
+		var myErr *mysql.MySQLError
+		if !errors.As(err, &myErr) || myErr.Number != 1062 {
+			t.Fatalf("got %+v want *mysql.MySQLError", err)
+		}
+		if qe.Args != nil || strings.Contains(err.Error(), "secret") || qe.Row != -1 {
+			t.Fatalf("got %+v want redacted args and no row", qe)
+		}
+	})
+
+	t.Run("WithErrorArgs", func(t *testing.T) {
+		db := NewDB(sqlDB, WithErrorArgs())
+		_, err := Exec(ctx, db, "INSERT INTO t VALUES (?)", "secret")
+		var qe *QueryError
+		if !errors.As(err, &qe) {
+			t.Fatalf("got %+v want *QueryError", err)
+		}
+		if diff := cmp.Diff(qe.Args, []any{"secret"}); diff != "" {
+			t.Fatalf("(-got +want) %s", diff)
+		}
+		if !strings.Contains(err.Error(), "args [secret]") {
+			t.Fatalf("got %q want args in message", err)
+		}
+	})
+}
@@ -0,0 +1,268 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"io"
+	"reflect"
+	"sync"
+	"testing"
+)
+
+// fakeResult is the canned response of a fakeHandler.
+type fakeResult struct {
+	columns      []string
+	types        []fakeColumnType // optional, by column
+	rows         [][]driver.Value
+	lastInsertID int64
+	rowsAffected int64
+}
+
+// fakeColumnType is the metadata of a column returned by fakeRows.
+type fakeColumnType struct {
+	dbType   string
+	scanType reflect.Type
+	nullable bool
+}
+
+// rowsOf returns a fakeResult with the given columns and rows.
+func rowsOf(columns []string, rows ...[]driver.Value) fakeResult {
+	return fakeResult{columns: columns, rows: rows}
+}
+
+// fakeHandler answers every query and statement sent to a fake database.
+type fakeHandler func(query string, args []driver.NamedValue) (fakeResult, error)
+
+// fakeConnector is an in-memory driver.Connector used to test the package
+// without a running database. Every query is answered by handler and logged.
+type fakeConnector struct {
+	handler fakeHandler
+
+	mu    sync.Mutex
+	log   []string
+	args  []any    // the args of the last statement
+	stmts []string // PREPARE and CLOSE events of prepared statements
+}
+
+// newFakeDB returns a *sql.DB backed by a fakeConnector using handler.
+func newFakeDB(t testing.TB, handler fakeHandler) (*sql.DB, *fakeConnector) {
+	t.Helper()
+	c := &fakeConnector{handler: handler}
+	db := sql.OpenDB(c)
+	t.Cleanup(func() { _ = db.Close() })
+	return db, c
+}
+
+func (c *fakeConnector) record(query string, args []driver.NamedValue) {
+	var values []any
+	for _, a := range args {
+		values = append(values, a.Value)
+	}
+	c.mu.Lock()
+	c.log = append(c.log, query)
+	c.args = values
+	c.mu.Unlock()
+}
+
+// queries returns the statements received so far.
+func (c *fakeConnector) queries() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.log...)
+}
+
+// lastQuery returns the last statement received, or "" if there is none.
+func (c *fakeConnector) lastQuery() string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if len(c.log) == 0 {
+		return ""
+	}
+	return c.log[len(c.log)-1]
+}
+
+// lastArgs returns the args of the last statement received.
+func (c *fakeConnector) lastArgs() []any {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.args
+}
+
+func (c *fakeConnector) recordStmt(event string) {
+	c.mu.Lock()
+	c.stmts = append(c.stmts, event)
+	c.mu.Unlock()
+}
+
+// stmtEvents returns the PREPARE and CLOSE events of prepared statements
+// so far.
+func (c *fakeConnector) stmtEvents() []string {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return append([]string(nil), c.stmts...)
+}
+
+func (c *fakeConnector) run(query string, args []driver.NamedValue) (fakeResult, error) {
+	c.record(query, args)
+	if c.handler == nil {
+		return fakeResult{}, nil
+	}
+	return c.handler(query, args)
+}
+
+func (c *fakeConnector) Connect(context.Context) (driver.Conn, error) { return &fakeConn{c: c}, nil }
+func (c *fakeConnector) Driver() driver.Driver                        { return fakeDriver{} }
+
+type fakeDriver struct{}
+
+func (fakeDriver) Open(string) (driver.Conn, error) {
+	return nil, errors.New("fakeDriver: use sql.OpenDB")
+}
+
+type fakeConn struct {
+	c *fakeConnector
+}
+
+func (c *fakeConn) Prepare(query string) (driver.Stmt, error) {
+	c.c.recordStmt("PREPARE " + query)
+	return &fakeStmt{c: c.c, query: query}, nil
+}
+
+func (c *fakeConn) Close() error { return nil }
+
+func (c *fakeConn) Begin() (driver.Tx, error) {
+	return c.BeginTx(context.Background(), driver.TxOptions{})
+}
+
+func (c *fakeConn) BeginTx(_ context.Context, _ driver.TxOptions) (driver.Tx, error) {
+	if _, err := c.c.run("BEGIN", nil); err != nil {
+		return nil, err
+	}
+	return &fakeTx{c: c.c}, nil
+}
+
+// CheckNamedValue converts args as database/sql does by default, but passes
+// slices through as arrays, like drivers such as pgx.
+func (c *fakeConn) CheckNamedValue(nv *driver.NamedValue) error {
+	v, err := driver.DefaultParameterConverter.ConvertValue(nv.Value)
+	if err != nil && nv.Value != nil && reflect.TypeOf(nv.Value).Kind() == reflect.Slice {
+		return nil
+	}
+	nv.Value = v
+	return err
+}
+
+func (c *fakeConn) QueryContext(_ context.Context, query string, args []driver.NamedValue) (driver.Rows, error) {
+	res, err := c.c.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: res.columns, types: res.types, rows: res.rows}, nil
+}
+
+func (c *fakeConn) ExecContext(_ context.Context, query string, args []driver.NamedValue) (driver.Result, error) {
+	res, err := c.c.run(query, args)
+	if err != nil {
+		return nil, err
+	}
+	return fakeExecResult(res), nil
+}
+
+type fakeStmt struct {
+	c     *fakeConnector
+	query string
+}
+
+func (s *fakeStmt) Close() error {
+	s.c.recordStmt("CLOSE " + s.query)
+	return nil
+}
+
+func (s *fakeStmt) NumInput() int { return -1 }
+
+func (s *fakeStmt) Exec(args []driver.Value) (driver.Result, error) {
+	res, err := s.c.run(s.query, namedValues(args))
+	if err != nil {
+		return nil, err
+	}
+	return fakeExecResult(res), nil
+}
+
+func (s *fakeStmt) Query(args []driver.Value) (driver.Rows, error) {
+	res, err := s.c.run(s.query, namedValues(args))
+	if err != nil {
+		return nil, err
+	}
+	return &fakeRows{columns: res.columns, types: res.types, rows: res.rows}, nil
+}
+
+func namedValues(args []driver.Value) []driver.NamedValue {
+	named := make([]driver.NamedValue, len(args))
+	for i, a := range args {
+		named[i] = driver.NamedValue{Ordinal: i + 1, Value: a}
+	}
+	return named
+}
+
+type fakeTx struct {
+	c *fakeConnector
+}
+
+func (tx *fakeTx) Commit() error {
+	_, err := tx.c.run("COMMIT", nil)
+	return err
+}
+
+func (tx *fakeTx) Rollback() error {
+	_, err := tx.c.run("ROLLBACK", nil)
+	return err
+}
+
+type fakeExecResult fakeResult
+
+func (r fakeExecResult) LastInsertId() (int64, error) { return r.lastInsertID, nil }
+func (r fakeExecResult) RowsAffected() (int64, error) { return r.rowsAffected, nil }
+
+type fakeRows struct {
+	columns []string
+	types   []fakeColumnType
+	rows    [][]driver.Value
+	pos     int
+}
+
+// the column type methods report nothing for columns without types, like
+// drivers that don't implement them
+func (r *fakeRows) ColumnTypeDatabaseTypeName(i int) string {
+	if i < len(r.types) {
+		return r.types[i].dbType
+	}
+	return ""
+}
+
+func (r *fakeRows) ColumnTypeScanType(i int) reflect.Type {
+	if i < len(r.types) {
+		return r.types[i].scanType
+	}
+	return reflect.TypeFor[any]()
+}
+
+func (r *fakeRows) ColumnTypeNullable(i int) (nullable, ok bool) {
+	if i < len(r.types) {
+		return r.types[i].nullable, true
+	}
+	return false, false
+}
+
+func (r *fakeRows) Columns() []string { return r.columns }
+func (r *fakeRows) Close() error      { return nil }
+
+func (r *fakeRows) Next(dest []driver.Value) error {
+	if r.pos >= len(r.rows) {
+		return io.EOF
+	}
+	copy(dest, r.rows[r.pos])
+	r.pos++
+	return nil
+}
@@ -0,0 +1,141 @@
+package dbx
+
+import (
+	"fmt"
+	"hash/fnv"
+	"regexp"
+	"strings"
+)
+
+// NormalizeQuery returns query with its literals and placeholders replaced
+// by ?, comments removed and whitespace collapsed, so that queries that only
+// differ in their values are normalized to the same string. Lists of values
+// are collapsed as well, so IN (?, ?, ?) becomes IN (?) and multi-row
+// VALUES lists are reduced to their first row.
+//
+// NormalizeQuery does not depend on a dialect: backslashes escape characters
+// in '...' strings and "..." is a quoted identifier, which is kept. Use
+// NormalizeQueryFor to normalize a query by the rules of its dialect.
+func NormalizeQuery(query string) string {
+	return normalizeQuery(quoting{backslash: true}, query)
+}
+
+// NormalizeQueryFor is like NormalizeQuery, but quoted literals follow the
+// rules of d. In MySQL, for example, "..." is a string and is replaced by ?.
+// If d is nil, it is the same as NormalizeQuery.
+func NormalizeQueryFor(d Dialect, query string) string {
+	if d == nil {
+		return NormalizeQuery(query)
+	}
+	return normalizeQuery(quotingOf(d), query)
+}
+
+func normalizeQuery(q quoting, query string) string {
+	var b strings.Builder
+	b.Grow(len(query))
+	space := false // whether whitespace precedes the next token
+	write := func(s string) {
+		if space && b.Len() > 0 && s != "," && s != ")" && !strings.HasSuffix(b.String(), "(") {
+			b.WriteByte(' ')
+		}
+		b.WriteString(s)
+		space = s == ","
+	}
+
+	for i := 0; i < len(query); {
+		c := query[i]
+		if end := skipLiteral(q, query, i); end > i {
+			switch {
+			case c == '\'', c == '"' && q.doubleQuotes:
+				write("?")
+			case c == '"', c == '`':
+				write(query[i:end]) // quoted identifier
+			default:
+				space = true // comment
+			}
+			i = end
+			continue
+		}
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			space = true
+			i++
+		case isIdentByte(c) && !isDigit(c):
+			j := i + 1
+			for j < len(query) && (isIdentByte(query[j]) || query[j] == '$') {
+				j++
+			}
+			if j < len(query) && query[j] == '\'' && isEscapeString(query, j) {
+				i = j // the E of an E'...' string, which is replaced by ?
+				continue
+			}
+			write(query[i:j])
+			i = j
+		case isDigit(c), c == '.' && i+1 < len(query) && isDigit(query[i+1]):
+			// numbers, including decimals, exponents and hex literals
+			j := i + 1
+			for j < len(query) && (isIdentByte(query[j]) || query[j] == '.') {
+				j++
+			}
+			write("?")
+			i = j
+		case c == '$' || c == ':' || c == '@':
+			// numbered placeholders: $1, :1 and @p1
+			j := i + 1
+			if c == '@' && j < len(query) && query[j] == 'p' {
+				j++
+			}
+			k := j
+			for k < len(query) && isDigit(query[k]) {
+				k++
+			}
+			if k > j {
+				write("?")
+				i = k
+				continue
+			}
+			write(query[i : i+1])
+			i++
+		default:
+			write(query[i : i+1])
+			i++
+		}
+	}
+
+	s := b.String()
+	s = inList.ReplaceAllString(s, "${1}(?)")
+	s = valuesList.ReplaceAllString(s, "$1")
+	return s
+}
+
+var (
+	inList     = regexp.MustCompile(`(?i)(\bIN ?)\((?:\?, )*\?\)`)
+	valuesList = regexp.MustCompile(`(?i)(\bVALUES ?\((?:\?, )*\?\))(?:, \((?:\?, )*\?\))+`)
+)
+
+// Fingerprint returns a short hash of the normalized query, which
+// identifies all queries that NormalizeQuery maps to the same string.
+func Fingerprint(query string) string {
+	return fingerprint(NormalizeQuery(query))
+}
+
+// FingerprintFor is like Fingerprint, but normalizes query with
+// NormalizeQueryFor.
+func FingerprintFor(d Dialect, query string) string {
+	return fingerprint(NormalizeQueryFor(d, query))
+}
+
+// fingerprint returns the Fingerprint of a normalized query.
+func fingerprint(normalized string) string {
+	h := fnv.New64a()
+	_, _ = h.Write([]byte(normalized))
+	return fmt.Sprintf("%016x", h.Sum64())
+}
+
+func isIdentByte(c byte) bool {
+	return c == '_' || isDigit(c) || 'a' <= c && c <= 'z' || 'A' <= c && c <= 'Z'
+}
+
+func isDigit(c byte) bool {
+	return '0' <= c && c <= '9'
+}
@@ -0,0 +1,73 @@
+package dbx
+
+import "testing"
+
+func TestNormalizeQuery(t *testing.T) {
+	tests := []struct {
+		name  string
+		query string
+		want  string
+	}{
+		{name: "whitespace", query: "SELECT a,\n\tb  FROM t", want: "SELECT a, b FROM t"},
+		{name: "numbers", query: "SELECT * FROM t WHERE id = 42 AND x > 1.5e3 OR y = 0xFF", want: "SELECT * FROM t WHERE id = ? AND x > ? OR y = ?"},
+		{name: "strings", query: `SELECT * FROM t WHERE name = 'O''Brien' AND s = 'a\'b'`, want: "SELECT * FROM t WHERE name = ? AND s = ?"},
+		{name: "identifiers", query: "SELECT `a1`, \"b2\", c3 FROM t1", want: "SELECT `a1`, \"b2\", c3 FROM t1"},
+		{name: "comments", query: "SELECT 1 -- one\nFROM /* the table */ t", want: "SELECT ? FROM t"},
+		{name: "placeholders", query: "SELECT * FROM t WHERE a = $1 AND b = :2 AND c = @p3 AND d = ?", want: "SELECT * FROM t WHERE a = ? AND b = ? AND c = ? AND d = ?"},
+		{name: "casts", query: "SELECT a::int FROM t", want: "SELECT a::int FROM t"},
+		{name: "in list", query: "SELECT * FROM t WHERE id IN (1, 2, 3) AND x in(?,?)", want: "SELECT * FROM t WHERE id IN (?) AND x in(?)"},
+		{name: "values", query: "INSERT INTO t (a, b) VALUES (?, ?), (?, ?), (1, 'x')", want: "INSERT INTO t (a, b) VALUES (?, ?)"},
+		{name: "parentheses", query: "SELECT COUNT( * ) FROM t", want: "SELECT COUNT(*) FROM t"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeQuery(tt.query); got != tt.want {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNormalizeQueryFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		dialect Dialect
+		query   string
+		want    string
+	}{
+		{name: "MySQL strings", dialect: MySQL, query: `SELECT * FROM t WHERE a = "bob" AND b = "it\"s" AND c = 'x'`, want: "SELECT * FROM t WHERE a = ? AND b = ? AND c = ?"},
+		{name: "MySQL comments", dialect: MySQL, query: "SELECT a # 'x'\nFROM t", want: "SELECT a FROM t"},
+		{name: "MySQL identifiers", dialect: MySQL, query: "SELECT `a1` FROM t1", want: "SELECT `a1` FROM t1"},
+		{name: "Postgres identifiers", dialect: Postgres, query: `SELECT "a1" FROM t1 WHERE b = 'C:\'`, want: `SELECT "a1" FROM t1 WHERE b = ?`},
+		{name: "Postgres escape strings", dialect: Postgres, query: `SELECT * FROM t WHERE a = E'it\'s' AND b = $1`, want: "SELECT * FROM t WHERE a = ? AND b = ?"},
+		{name: "nil", query: `SELECT "a1" FROM t1 WHERE b = 'x'`, want: `SELECT "a1" FROM t1 WHERE b = ?`},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := NormalizeQueryFor(tt.dialect, tt.query); got != tt.want {
+				t.Fatalf("got %q want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFingerprint(t *testing.T) {
+	a := Fingerprint("SELECT * FROM t WHERE id IN (1, 2) AND name = 'a'")
+	b := Fingerprint("select_is_different")
+	c := Fingerprint("SELECT *  FROM t WHERE id IN (?, ?, ?) AND name = ?")
+	if len(a) != 16 {
+		t.Fatalf("got %q want 16 hex digits", a)
+	}
+	if a != c {
+		t.Fatalf("got %s != %s want equal fingerprints", a, c)
+	}
+	if a == b {
+		t.Fatalf("got %s == %s want different fingerprints", a, b)
+	}
+
+	bob := FingerprintFor(MySQL, `SELECT * FROM t WHERE name = "bob"`)
+	alice := FingerprintFor(MySQL, `SELECT * FROM t WHERE name = "alice"`)
+	if bob != alice {
+		t.Fatalf("got %s != %s want equal fingerprints of MySQL strings", bob, alice)
+	}
+}
@@ -56,3 +56,98 @@ func parseName(field reflect.StructField, tagName string, mapFunc, tagMapFunc ma
 	fieldName, _, _ = strings.Cut(tag, ",")
 	return tag, fieldName
 }
+
+// parseOptions parses the options that follow the name in a tag, e.g.
+// "auto" and "size=10" in `db:"id,auto,size=10"`. Options without a value
+// are mapped to the empty string. It returns nil if there are no options.
+func parseOptions(tag string) map[string]string {
+	_, opts, ok := strings.Cut(tag, ",")
+	if !ok {
+		return nil
+	}
+	options := make(map[string]string)
+	for _, opt := range strings.Split(opts, ",") {
+		k, v, _ := strings.Cut(strings.TrimSpace(opt), "=")
+		if k != "" {
+			options[k] = v
+		}
+	}
+	return options
+}
+
+// quoting describes how the literals of a Dialect are quoted.
+type quoting struct {
+	backslash    bool // whether backslashes escape characters in strings
+	doubleQuotes bool // whether "..." is a string rather than an identifier
+	hashComments bool // whether # starts a comment
+}
+
+// quotingOf returns the quoting of d.
+func quotingOf(d Dialect) quoting {
+	caps := d.Capabilities()
+	return quoting{backslash: caps.BackslashEscapes, doubleQuotes: caps.DoubleQuotedStrings, hashComments: caps.HashComments}
+}
+
+// skipLiteral returns the index just past the quoted string, quoted
+// identifier or comment that starts at query[i]. If nothing of the sort
+// starts at i, i is returned unchanged. Unterminated literals extend to the
+// end of the query. Backslashes escape characters in strings if q allows
+// it, and in Postgres E'...' strings, but never in quoted identifiers. #
+// starts a line comment if q allows it.
+func skipLiteral(q quoting, query string, i int) int {
+	switch c := query[i]; c {
+	case '\'', '"', '`':
+		str := c == '\'' || c == '"' && q.doubleQuotes
+		backslash := q.backslash && str || c == '\'' && isEscapeString(query, i)
+		for j := i + 1; j < len(query); j++ {
+			switch query[j] {
+			case '\\':
+				if backslash {
+					j++ // skip the escaped character
+				}
+			case c:
+				// a doubled quote is an escaped quote, not the end of the literal
+				if j+1 < len(query) && query[j+1] == c {
+					j++
+					continue
+				}
+				return j + 1
+			}
+		}
+		return len(query)
+	case '-':
+		if strings.HasPrefix(query[i:], "--") {
+			return lineEnd(query, i)
+		}
+	case '#':
+		if q.hashComments {
+			return lineEnd(query, i)
+		}
+	case '/':
+		if strings.HasPrefix(query[i:], "/*") {
+			if end := strings.Index(query[i+2:], "*/"); end >= 0 {
+				return i + 2 + end + 2
+			}
+			return len(query)
+		}
+	}
+	return i
+}
+
+// lineEnd returns the index just past the end of the line that contains
+// query[i], or the end of the query.
+func lineEnd(query string, i int) int {
+	if end := strings.IndexByte(query[i:], '\n'); end >= 0 {
+		return i + end + 1
+	}
+	return len(query)
+}
+
+// isEscapeString reports whether the string that starts at query[i] is a
+// Postgres escape string, e.g. E'\n'.
+func isEscapeString(query string, i int) bool {
+	if i == 0 || query[i-1] != 'E' && query[i-1] != 'e' {
+		return false
+	}
+	return i == 1 || !isNameByte(query[i-2], false)
+}
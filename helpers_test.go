@@ -71,20 +71,15 @@ func RunWithSchemaContext(ctx context.Context, schema Schema, t testing.TB, test
 		test(ctx, db, t)
 	}
 
+	if mysqldb == nil {
+		t.Skip("MySQL is not available")
+	}
 	create, drop, now := schema.MySQL()
 	runner(ctx, mysqldb, t, create, drop, now)
 }
 
-func Open(driverName, dataSourceName string) (*sql.DB, error) {
-	db, err := sql.Open(driverName, dataSourceName)
-	if err != nil {
-		return nil, err
-	}
-	return db, nil
-}
-
 func Connect(driverName, dataSourceName string) (*sql.DB, error) {
-	db, err := Open(driverName, dataSourceName)
+	db, err := sql.Open(driverName, dataSourceName)
 	if err != nil {
 		return nil, err
 	}
@@ -132,12 +127,8 @@ func loadDefaultFixtureContext(ctx context.Context, db *sql.DB, t testing.TB) {
 	tx.ExecContext(ctx, "INSERT INTO place (country, telcode) VALUES (?, ?)", "Hong Kong", "852")
 	tx.ExecContext(ctx, "INSERT INTO place (country, telcode) VALUES (?, ?)", "Singapore", "65")
 
-	// TODO 2024/02/24 @Jimeux bind type setting?
-	/*	if db.DriverName() == "mysql" {
-			tx.ExecContext(ctx, tx.Rebind("INSERT INTO capplace (`COUNTRY`, `TELCODE`) VALUES (?, ?)"), "Sarf Efrica", "27")
-		} else {
-			tx.ExecContext(ctx, tx.Rebind("INSERT INTO capplace (\"COUNTRY\", \"TELCODE\") VALUES (?, ?)"), "Sarf Efrica", "27")
-		}*/
+	d := dialectOf(db.Driver())
+	tx.ExecContext(ctx, Rebind(d, "INSERT INTO capplace ("+d.Quote("COUNTRY")+", "+d.Quote("TELCODE")+") VALUES (?, ?)"), "Sarf Efrica", "27")
 	tx.ExecContext(ctx, "INSERT INTO employees (name, id) VALUES (?, ?)", "Peter", "4444")
 	tx.ExecContext(ctx, "INSERT INTO employees (name, id, boss_id) VALUES (?, ?, ?)", "Joe", "1", "4444")
 	tx.ExecContext(ctx, "INSERT INTO employees (name, id, boss_id) VALUES (?, ?, ?)", "Martin", "2", "4444")
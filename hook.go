@@ -0,0 +1,187 @@
+package dbx
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// QueryKind is the kind of statement reported to a Hook.
+type QueryKind int
+
+const (
+	// QuerySelect is a query that returns rows, run by Get, Select and the
+	// functions built on them.
+	QuerySelect QueryKind = iota
+	// QueryExec is a statement run by Exec and the functions built on it,
+	// such as Insert, or the creation of a savepoint.
+	QueryExec
+	// QueryBegin is the start of a transaction.
+	QueryBegin
+	// QueryCommit is the commit of a transaction or the release of a savepoint.
+	QueryCommit
+	// QueryRollback is the rollback of a transaction or to a savepoint.
+	QueryRollback
+)
+
+func (k QueryKind) String() string {
+	switch k {
+	case QuerySelect:
+		return "select"
+	case QueryExec:
+		return "exec"
+	case QueryBegin:
+		return "begin"
+	case QueryCommit:
+		return "commit"
+	case QueryRollback:
+		return "rollback"
+	default:
+		return "unknown"
+	}
+}
+
+// QueryInfo describes a statement reported to a Hook.
+type QueryInfo struct {
+	Kind    QueryKind
+	Query   string // the query as sent to the database, or e.g. "BEGIN" for transactions
+	Name    string // the name set with WithQueryName, if any
+	Args    []any
+	Tx      bool      // whether the statement runs in a transaction
+	Dialect Dialect   // the Dialect of the DB
+	Start   time.Time // when BeforeQuery was called
+	// Duration is the time from Start until the statement completed. It is
+	// only set in AfterQuery. For queries that return rows it includes the
+	// time the caller spent iterating over them.
+	Duration time.Duration
+}
+
+// A Hook is called before and after each statement run through a DB or its
+// transactions. Use WithHooks to register hooks.
+type Hook interface {
+	// BeforeQuery is called before the statement is sent to the database.
+	// The returned context is used to run the statement and is passed to
+	// AfterQuery, so it can carry values such as a start time or a span.
+	BeforeQuery(ctx context.Context, info QueryInfo) context.Context
+	// AfterQuery is called when the statement has completed. For queries
+	// that return rows, that is when the rows have all been scanned, an
+	// error occurred or the caller stopped iterating, and rowsScanned is the
+	// number of rows scanned. It is 0 for other kinds of statements.
+	AfterQuery(ctx context.Context, info QueryInfo, rowsScanned int, err error)
+}
+
+// WithHooks adds hooks to the DB. BeforeQuery is called on the hooks in
+// the order they were added, each receiving the context returned by the
+// previous one, and AfterQuery in the reverse order with the context
+// returned by the last one.
+func WithHooks(hooks ...Hook) Option {
+	return func(db *DB) {
+		for _, h := range hooks {
+			if h != nil {
+				db.hooks = append(db.hooks, h)
+			}
+		}
+	}
+}
+
+// beforeQuery calls the BeforeQuery hooks of db, and returns the resulting
+// context and a function to call the AfterQuery hooks with. tx reports
+// whether the statement runs in a transaction. It is safe to call on a nil
+// *DB.
+func (db *DB) beforeQuery(ctx context.Context, tx bool, kind QueryKind, query string, args []any) (context.Context, func(rowsScanned int, err error)) {
+	if db == nil || len(db.hooks) == 0 {
+		return ctx, func(int, error) {}
+	}
+	info := QueryInfo{Kind: kind, Query: query, Name: QueryName(ctx), Args: args, Tx: tx, Dialect: db.dialect, Start: time.Now()}
+	for _, h := range db.hooks {
+		ctx = h.BeforeQuery(ctx, info)
+	}
+	return ctx, func(rowsScanned int, err error) {
+		info.Duration = time.Since(info.Start)
+		for i := len(db.hooks) - 1; i >= 0; i-- {
+			db.hooks[i].AfterQuery(ctx, info, rowsScanned, err)
+		}
+	}
+}
+
+// SlogHook is a Hook that logs every statement.
+type SlogHook struct {
+	Logger *slog.Logger // slog.Default() if nil
+	// Level is the level of statements that succeed. Statements that fail
+	// are logged at slog.LevelError.
+	Level slog.Level
+	// LogArgs includes the args of statements in the log. They are omitted
+	// by default, as they may contain personal data or secrets.
+	LogArgs bool
+}
+
+func (h *SlogHook) BeforeQuery(ctx context.Context, _ QueryInfo) context.Context {
+	return ctx
+}
+
+func (h *SlogHook) AfterQuery(ctx context.Context, info QueryInfo, rowsScanned int, err error) {
+	level := h.Level
+	if err != nil {
+		level = slog.LevelError
+	}
+	logger := h.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	if !logger.Enabled(ctx, level) {
+		return
+	}
+	attrs := queryAttrs(info, rowsScanned, h.LogArgs)
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	logger.LogAttrs(ctx, level, "query", attrs...)
+}
+
+// SlowQueryHook is a Hook that logs statements that take at least
+// Threshold at slog.LevelWarn.
+type SlowQueryHook struct {
+	Threshold time.Duration
+	Logger    *slog.Logger // slog.Default() if nil
+	LogArgs   bool         // see SlogHook
+}
+
+func (h *SlowQueryHook) BeforeQuery(ctx context.Context, _ QueryInfo) context.Context {
+	return ctx
+}
+
+func (h *SlowQueryHook) AfterQuery(ctx context.Context, info QueryInfo, rowsScanned int, err error) {
+	if info.Duration < h.Threshold {
+		return
+	}
+	logger := h.Logger
+	if logger == nil {
+		logger = slog.Default()
+	}
+	attrs := append(queryAttrs(info, rowsScanned, h.LogArgs), slog.Duration("threshold", h.Threshold))
+	if err != nil {
+		attrs = append(attrs, slog.Any("error", err))
+	}
+	logger.LogAttrs(ctx, slog.LevelWarn, "slow query", attrs...)
+}
+
+func queryAttrs(info QueryInfo, rowsScanned int, logArgs bool) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("kind", info.Kind.String()),
+		slog.String("query", info.Query),
+		slog.Duration("duration", info.Duration),
+	}
+	if info.Name != "" {
+		attrs = append(attrs, slog.String("name", info.Name))
+	}
+	if info.Kind == QuerySelect {
+		attrs = append(attrs, slog.Int("rows", rowsScanned))
+	}
+	if info.Tx {
+		attrs = append(attrs, slog.Bool("tx", true))
+	}
+	if logArgs && len(info.Args) > 0 {
+		attrs = append(attrs, slog.Any("args", info.Args))
+	}
+	return attrs
+}
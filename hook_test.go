@@ -0,0 +1,189 @@
+package dbx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type hookKey struct{}
+
+// recordingHook records the calls made to it as strings.
+type recordingHook struct {
+	name  string
+	calls *[]string
+}
+
+func (h recordingHook) BeforeQuery(ctx context.Context, info QueryInfo) context.Context {
+	*h.calls = append(*h.calls, fmt.Sprintf("%s before %s %q tx=%t", h.name, info.Kind, info.Query, info.Tx))
+	return context.WithValue(ctx, hookKey{}, fmt.Sprint(ctx.Value(hookKey{}), h.name))
+}
+
+func (h recordingHook) AfterQuery(ctx context.Context, info QueryInfo, rowsScanned int, err error) {
+	if info.Start.IsZero() || info.Duration < 0 {
+		*h.calls = append(*h.calls, "missing start or duration")
+	}
+	*h.calls = append(*h.calls, fmt.Sprintf("%s after %s %q ctx=%v rows=%d err=%v",
+		h.name, info.Kind, info.Query, ctx.Value(hookKey{}), rowsScanned, err != nil))
+}
+
+func TestHooks(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	db, _ := txDB(t, map[string]error{"UPDATE fail": errors.New("fail")},
+		WithHooks(recordingHook{name: "a", calls: &calls}, nil, recordingHook{name: "b", calls: &calls}))
+
+	tests := []struct {
+		name string
+		run  func(t *testing.T)
+		want []string
+	}{
+		{
+			name: "Select",
+			run: func(t *testing.T) {
+				names, err := Select[string](ctx, db, "SELECT first_name FROM person").Collect()
+				if err != nil || len(names) != 2 {
+					t.Fatalf("got %v, %+v want 2 names", names, err)
+				}
+			},
+			want: []string{
+				`a before select "SELECT first_name FROM person" tx=false`,
+				`b before select "SELECT first_name FROM person" tx=false`,
+				`b after select "SELECT first_name FROM person" ctx=<nil>ab rows=2 err=false`,
+				`a after select "SELECT first_name FROM person" ctx=<nil>ab rows=2 err=false`,
+			},
+		},
+		{
+			name: "Select break",
+			run: func(t *testing.T) {
+				for range Select[string](ctx, db, "SELECT first_name FROM person") {
+					calls = append(calls, "break")
+					break
+				}
+			},
+			want: []string{
+				`a before select "SELECT first_name FROM person" tx=false`,
+				`b before select "SELECT first_name FROM person" tx=false`,
+				"break",
+				`b after select "SELECT first_name FROM person" ctx=<nil>ab rows=1 err=false`,
+				`a after select "SELECT first_name FROM person" ctx=<nil>ab rows=1 err=false`,
+			},
+		},
+		{
+			name: "Get error",
+			run: func(t *testing.T) {
+				if _, err := Get[int](ctx, db, "SELECT first_name FROM person"); err == nil {
+					t.Fatal("got nil want error")
+				}
+			},
+			want: []string{
+				`a before select "SELECT first_name FROM person" tx=false`,
+				`b before select "SELECT first_name FROM person" tx=false`,
+				`b after select "SELECT first_name FROM person" ctx=<nil>ab rows=0 err=true`,
+				`a after select "SELECT first_name FROM person" ctx=<nil>ab rows=0 err=true`,
+			},
+		},
+		{
+			name: "Exec",
+			run: func(t *testing.T) {
+				if _, err := Exec(ctx, db, "UPDATE fail"); err == nil {
+					t.Fatal("got nil want error")
+				}
+			},
+			want: []string{
+				`a before exec "UPDATE fail" tx=false`,
+				`b before exec "UPDATE fail" tx=false`,
+				`b after exec "UPDATE fail" ctx=<nil>ab rows=0 err=true`,
+				`a after exec "UPDATE fail" ctx=<nil>ab rows=0 err=true`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			calls = nil
+			tt.run(t)
+			if diff := cmp.Diff(calls, tt.want); diff != "" {
+				t.Fatalf("(-got +want) %s", diff)
+			}
+		})
+	}
+}
+
+func TestHooksTx(t *testing.T) {
+	ctx := context.Background()
+	var calls []string
+	db, _ := txDB(t, map[string]error{"UPDATE fail": errors.New("fail")},
+		WithHooks(recordingHook{name: "a", calls: &calls}))
+
+	err := db.WithTx(ctx, nil, func(tx *Tx) error {
+		if _, err := Get[string](ctx, tx, "SELECT first_name FROM person"); err != nil {
+			return err
+		}
+		_ = tx.WithTx(ctx, func(tx *Tx) error {
+			_, err := Exec(ctx, tx, "UPDATE fail")
+			return err
+		})
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	want := []string{
+		`a before begin "BEGIN" tx=true`,
+		`a after begin "BEGIN" ctx=<nil>a rows=0 err=false`,
+		`a before select "SELECT first_name FROM person" tx=true`,
+		`a after select "SELECT first_name FROM person" ctx=<nil>a rows=1 err=false`,
+		`a before exec "SAVEPOINT sp_1" tx=true`,
+		`a after exec "SAVEPOINT sp_1" ctx=<nil>a rows=0 err=false`,
+		`a before exec "UPDATE fail" tx=true`,
+		`a after exec "UPDATE fail" ctx=<nil>a rows=0 err=true`,
+		`a before rollback "ROLLBACK TO SAVEPOINT sp_1" tx=true`,
+		`a after rollback "ROLLBACK TO SAVEPOINT sp_1" ctx=<nil>a rows=0 err=false`,
+		`a before commit "COMMIT" tx=true`,
+		`a after commit "COMMIT" ctx=<nil>a rows=0 err=false`,
+	}
+	if diff := cmp.Diff(calls, want); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+}
+
+func TestSlogHooks(t *testing.T) {
+	ctx := context.Background()
+	var buf bytes.Buffer
+	logger := slog.New(slog.NewTextHandler(&buf, &slog.HandlerOptions{
+		Level: slog.LevelDebug,
+		ReplaceAttr: func(_ []string, a slog.Attr) slog.Attr {
+			if a.Key == slog.TimeKey || a.Key == "duration" {
+				return slog.Attr{}
+			}
+			return a
+		},
+	}))
+	db, _ := txDB(t, map[string]error{"UPDATE fail": errors.New("fail")}, WithHooks(
+		&SlogHook{Logger: logger, Level: slog.LevelDebug},
+		&SlowQueryHook{Threshold: 0, Logger: logger, LogArgs: true},
+		&SlowQueryHook{Threshold: time.Hour, Logger: logger},
+	))
+
+	if _, err := Get[string](ctx, db, "SELECT first_name FROM person WHERE id = ?", 1); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	_, _ = Exec(ctx, db, "UPDATE fail")
+
+	want := []string{
+		`level=WARN msg="slow query" kind=select query="SELECT first_name FROM person WHERE id = ?" rows=1 args=[1] threshold=0s`,
+		`level=DEBUG msg=query kind=select query="SELECT first_name FROM person WHERE id = ?" rows=1`,
+		`level=WARN msg="slow query" kind=exec query="UPDATE fail" threshold=0s error="query \"UPDATE fail\": fail"`,
+		`level=ERROR msg=query kind=exec query="UPDATE fail" error="query \"UPDATE fail\": fail"`,
+	}
+	if diff := cmp.Diff(strings.Split(strings.TrimSpace(buf.String()), "\n"), want); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+}
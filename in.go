@@ -0,0 +1,131 @@
+package dbx
+
+import (
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+var _valuerInterface = reflect.TypeOf((*driver.Valuer)(nil)).Elem()
+
+// In expands slice arguments in args into one placeholder per element, so
+// that a query like "SELECT * FROM person WHERE id IN (?)" can be called
+// with a []int64. The returned args are flattened to match the returned
+// query. Byte slices and types implementing driver.Valuer are passed
+// through unchanged. An empty slice is an error, as "IN ()" is not valid SQL.
+//
+// Select, Get and Exec call In for every query with ? placeholders, so
+// calling it directly is only needed when running queries through
+// database/sql. Literals are quoted as in MySQL.
+func In(query string, args ...any) (string, []any, error) {
+	return in(quotingOf(MySQL), query, args)
+}
+
+// in is In for queries whose literals are quoted as described by q.
+func in(q quoting, query string, args []any) (string, []any, error) {
+	// lengths of the slices to expand, or -1 for args passed through unchanged
+	var lens []int
+	flatLen := 0
+	for i, arg := range args {
+		n := expandLen(arg)
+		if n == 0 {
+			return "", nil, fmt.Errorf("empty slice passed as argument %d of IN query", i+1)
+		}
+		if n > 0 && lens == nil {
+			lens = make([]int, len(args))
+			for j := range i {
+				lens[j] = -1
+			}
+		}
+		if lens != nil {
+			lens[i] = n
+		}
+		flatLen += max(n, 1)
+	}
+	// fast path: nothing to expand
+	if lens == nil {
+		return query, args, nil
+	}
+
+	var b strings.Builder
+	b.Grow(len(query) + 3*(flatLen-len(args)))
+	flat := make([]any, 0, flatLen)
+	argPos := 0
+
+	for i := 0; i < len(query); {
+		if end := skipLiteral(q, query, i); end > i {
+			b.WriteString(query[i:end])
+			i = end
+			continue
+		}
+		c := query[i]
+		i++
+		if c != '?' {
+			b.WriteByte(c)
+			continue
+		}
+		if argPos >= len(args) {
+			return "", nil, errors.New("number of placeholders exceeds number of arguments")
+		}
+		if n := lens[argPos]; n > 0 {
+			v := reflect.ValueOf(args[argPos])
+			for j := range n {
+				if j > 0 {
+					b.WriteString(", ")
+				}
+				b.WriteByte('?')
+				flat = append(flat, v.Index(j).Interface())
+			}
+		} else {
+			b.WriteByte('?')
+			flat = append(flat, args[argPos])
+		}
+		argPos++
+	}
+
+	if argPos != len(args) {
+		return "", nil, fmt.Errorf("number of placeholders (%d) less than number of arguments (%d)", argPos, len(args))
+	}
+	return b.String(), flat, nil
+}
+
+// hasPlaceholder reports whether query has a ? placeholder outside quoted
+// strings, quoted identifiers and comments.
+func hasPlaceholder(q quoting, query string) bool {
+	for i := 0; i < len(query); {
+		if end := skipLiteral(q, query, i); end > i {
+			i = end
+			continue
+		}
+		if query[i] == '?' {
+			return true
+		}
+		i++
+	}
+	return false
+}
+
+// expandLen returns the number of elements of arg if In should expand it,
+// or -1 if it should be passed through unchanged.
+func expandLen(arg any) int {
+	if arg == nil {
+		return -1
+	}
+	if _, ok := arg.(driver.Valuer); ok {
+		return -1
+	}
+	t := reflect.TypeOf(arg)
+	if t.Kind() != reflect.Slice && t.Kind() != reflect.Array {
+		return -1
+	}
+	// []byte and types based on it, e.g. json.RawMessage, are single values
+	if t.Elem().Kind() == reflect.Uint8 {
+		return -1
+	}
+	if reflect.PointerTo(t).Implements(_valuerInterface) {
+		return -1
+	}
+	return reflect.ValueOf(arg).Len()
+}
@@ -0,0 +1,222 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"encoding/json"
+	"errors"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestIn(t *testing.T) {
+	tests := []struct {
+		name      string
+		query     string
+		args      []any
+		wantQuery string
+		wantArgs  []any
+		wantErr   string
+	}{
+		{
+			name:      "no slices",
+			query:     "SELECT * FROM person WHERE id = ? AND name = ?",
+			args:      []any{1, "a"},
+			wantQuery: "SELECT * FROM person WHERE id = ? AND name = ?",
+			wantArgs:  []any{1, "a"},
+		},
+		{
+			name:      "int64 slice",
+			query:     "SELECT * FROM person WHERE id IN (?)",
+			args:      []any{[]int64{1, 2, 3}},
+			wantQuery: "SELECT * FROM person WHERE id IN (?, ?, ?)",
+			wantArgs:  []any{int64(1), int64(2), int64(3)},
+		},
+		{
+			name:      "mixed args",
+			query:     "SELECT * FROM person WHERE a = ? AND id IN (?) AND b = ? AND name IN (?)",
+			args:      []any{"a", []int{1, 2}, "b", [1]string{"x"}},
+			wantQuery: "SELECT * FROM person WHERE a = ? AND id IN (?, ?) AND b = ? AND name IN (?)",
+			wantArgs:  []any{"a", 1, 2, "b", "x"},
+		},
+		{
+			name:      "placeholders in literals",
+			query:     "SELECT '?', `?` FROM t -- ?\nWHERE id IN (?)",
+			args:      []any{[]int{1, 2}},
+			wantQuery: "SELECT '?', `?` FROM t -- ?\nWHERE id IN (?, ?)",
+			wantArgs:  []any{1, 2},
+		},
+		{
+			name:      "bytes and valuers are not expanded",
+			query:     "SELECT * FROM t WHERE a = ? AND b = ? AND c = ? AND id IN (?)",
+			args:      []any{[]byte("a"), json.RawMessage(`{}`), valuerSlice{1, 2}, []int{1}},
+			wantQuery: "SELECT * FROM t WHERE a = ? AND b = ? AND c = ? AND id IN (?)",
+			wantArgs:  []any{[]byte("a"), json.RawMessage(`{}`), valuerSlice{1, 2}, 1},
+		},
+		{
+			name:    "empty slice",
+			query:   "SELECT * FROM person WHERE id IN (?)",
+			args:    []any{[]int{}},
+			wantErr: "empty slice passed as argument 1",
+		},
+		{
+			name:    "nil slice",
+			query:   "SELECT * FROM person WHERE a = ? AND id IN (?)",
+			args:    []any{1, []int(nil)},
+			wantErr: "empty slice passed as argument 2",
+		},
+		{
+			name:    "too few placeholders",
+			query:   "SELECT * FROM person WHERE id IN (?)",
+			args:    []any{[]int{1}, 2},
+			wantErr: "less than number of arguments",
+		},
+		{
+			name:    "too many placeholders",
+			query:   "SELECT * FROM person WHERE id IN (?) AND a = ?",
+			args:    []any{[]int{1}},
+			wantErr: "exceeds number of arguments",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query, args, err := In(tt.query, tt.args...)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %+v want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got %+v want nil", err)
+			}
+			if query != tt.wantQuery {
+				t.Fatalf("got %q want %q", query, tt.wantQuery)
+			}
+			if !cmp.Equal(args, tt.wantArgs) {
+				t.Fatalf("(-got +want) %s", cmp.Diff(args, tt.wantArgs))
+			}
+		})
+	}
+}
+
+type valuerSlice []int
+
+func (v valuerSlice) Value() (driver.Value, error) { return "valuer", nil }
+
+func TestInQueries(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, conn := newFakeDB(t, func(query string, args []driver.NamedValue) (fakeResult, error) {
+		if strings.HasSuffix(query, "RETURNING (xmax = 0)") {
+			return rowsOf([]string{"inserted"}, []driver.Value{true}), nil
+		}
+		return personRows(query, args)
+	})
+	ids := []int64{1, 2}
+	const wantQuery = "SELECT first_name FROM person WHERE id IN (?, ?)"
+	wantArgs := []any{int64(1), int64(2)}
+
+	checkLast := func(t *testing.T) {
+		t.Helper()
+		if got := conn.lastQuery(); got != wantQuery {
+			t.Fatalf("got %q want %q", got, wantQuery)
+		}
+		if got := conn.lastArgs(); !cmp.Equal(got, wantArgs) {
+			t.Fatalf("(-got +want) %s", cmp.Diff(got, wantArgs))
+		}
+	}
+
+	t.Run("Select", func(t *testing.T) {
+		if _, err := Select[string](ctx, sqlDB, "SELECT first_name FROM person WHERE id IN (?)", ids).Collect(); err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		checkLast(t)
+	})
+	t.Run("Get", func(t *testing.T) {
+		if _, err := Get[string](ctx, NewDB(sqlDB), "SELECT first_name FROM person WHERE id IN (?)", ids); err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		checkLast(t)
+	})
+	t.Run("Exec", func(t *testing.T) {
+		if _, err := Exec(ctx, sqlDB, "SELECT first_name FROM person WHERE id IN (?)", ids); err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		checkLast(t)
+	})
+	t.Run("NamedSelect", func(t *testing.T) {
+		_, err := NamedSelect[string](ctx, sqlDB, "SELECT first_name FROM person WHERE id IN (:ids)", map[string]any{"ids": ids}).Collect()
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		checkLast(t)
+	})
+	t.Run("NamedExec", func(t *testing.T) {
+		_, err := NamedExec(ctx, sqlDB, "SELECT first_name FROM person WHERE id IN (:ids)", struct {
+			IDs []int64 `db:"ids"`
+		}{ids})
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		checkLast(t)
+	})
+	t.Run("no placeholders", func(t *testing.T) {
+		// slices are passed as arrays to queries with $n placeholders
+		const query = "SELECT first_name FROM person WHERE id = ANY($1)"
+		if _, err := Select[string](ctx, sqlDB, query, ids).Collect(); err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		if _, err := Exec(ctx, NewDB(sqlDB, WithDialect(Postgres)), query, ids); err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		queries := conn.queries()
+		if diff := cmp.Diff(queries[len(queries)-2:], []string{query, query}); diff != "" {
+			t.Fatalf("(-got +want) %s", diff)
+		}
+		if diff := cmp.Diff(conn.lastArgs(), []any{ids}); diff != "" {
+			t.Fatalf("(-got +want) %s", diff)
+		}
+	})
+	t.Run("column values", func(t *testing.T) {
+		// slice fields are column values, e.g. of Postgres arrays
+		type tagged struct {
+			ID   int64    `db:"id,pk"`
+			Tags []string `db:"tags"`
+		}
+		db := NewDB(sqlDB, WithDialect(Postgres))
+		v := &tagged{ID: 1, Tags: []string{"a", "b"}}
+		for _, run := range []func() error{
+			func() error { _, err := Insert(ctx, db, "tagged", v); return err },
+			func() error { _, err := Update(ctx, db, "tagged", v); return err },
+			func() error { _, err := Upsert(ctx, db, "tagged", v); return err },
+		} {
+			if err := run(); err != nil && !errors.Is(err, ErrNoRowsAffected) {
+				t.Fatalf("got %+v want nil", err)
+			}
+			if got := conn.lastArgs(); len(got) != 2 || !slices.ContainsFunc(got, func(a any) bool { return cmp.Equal(a, v.Tags) }) {
+				t.Fatalf("got %v want the id and %v", got, v.Tags)
+			}
+		}
+	})
+	t.Run("empty slice", func(t *testing.T) {
+		_, err := Get[string](ctx, sqlDB, "SELECT first_name FROM person WHERE id IN (?)", []int64{})
+		if err == nil || !strings.Contains(err.Error(), "empty slice") {
+			t.Fatalf("got %+v want empty slice error", err)
+		}
+		var qe *QueryError
+		if !errors.As(err, &qe) {
+			t.Fatalf("got %+v want a QueryError", err)
+		}
+		var res sql.Result
+		if res, err = Exec(ctx, sqlDB, "DELETE FROM person WHERE id IN (?)", []int64{}); err == nil {
+			t.Fatalf("got %+v want empty slice error", res)
+		}
+		if !errors.As(err, &qe) || qe.Query != "DELETE FROM person WHERE id IN (?)" {
+			t.Fatalf("got %+v want a QueryError of the query", err)
+		}
+	})
+}
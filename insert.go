@@ -0,0 +1,312 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// Insert inserts v into table. The columns and their values are taken from
+// the fields of T that are mapped by the Mapper of e, in the order the
+// fields are declared. Fields of embedded structs are included the same way
+// they are matched when scanning, and fields of nested (non-embedded)
+// structs are not.
+//
+// Fields can be excluded with tag options:
+//   - `db:"id,auto"` marks an auto-increment key. It is never inserted, and
+//     if the Dialect supports it, the value of LastInsertId is written back
+//     to the field after the insert.
+//   - `db:"created_at,readonly"` marks a column that is set by the database.
+func Insert[T any](ctx context.Context, e Execer, table string, v *T) (sql.Result, error) {
+	db := dbOf(e)
+	if v == nil {
+		return nil, db.handleErr(fmt.Errorf("cannot insert nil %T", v))
+	}
+	cols, auto, err := insertColumns(db.mapperOrDefault(), reflect.TypeFor[T]())
+	if err != nil {
+		return nil, db.handleErr(err)
+	}
+
+	d := db.dialectOrDefault()
+	rv := reflect.ValueOf(v).Elem()
+	args, err := columnValues(rv, cols, nil)
+	if err != nil {
+		return nil, db.handleErr(err)
+	}
+	res, err := exec(ctx, e, insertQuery(d, table, cols, 1), args, false)
+	if err != nil {
+		return nil, err
+	}
+
+	if auto != nil && d.Capabilities().LastInsertID {
+		id, err := res.LastInsertId()
+		if err != nil {
+			return res, db.handleErr(err)
+		}
+		if err := setInt(fieldByIndexes(rv, auto.Traversal), id); err != nil {
+			return res, db.handleErr(fmt.Errorf("failed to set %s of %T: %w", auto.Name, v, err))
+		}
+	}
+	return res, nil
+}
+
+// DefaultMaxPacketSize is the default estimated size limit in bytes of a
+// statement sent by InsertMany. It matches the default max_allowed_packet
+// of MySQL 5.7.
+const DefaultMaxPacketSize = 4 << 20
+
+// InsertManyOptions configures how InsertMany splits rows into batches.
+// The zero value uses the limits of the Dialect and DefaultMaxPacketSize.
+type InsertManyOptions struct {
+	// BatchSize is the maximum number of rows per statement. 0 means no limit
+	// other than MaxPlaceholders and MaxPacketSize.
+	BatchSize int
+	// MaxPlaceholders is the maximum number of placeholders per statement.
+	// 0 means the MaxPlaceholders capability of the Dialect.
+	MaxPlaceholders int
+	// MaxPacketSize is the maximum estimated size in bytes of a statement and
+	// its args. 0 means DefaultMaxPacketSize. A single row that exceeds the
+	// limit is still sent on its own.
+	MaxPacketSize int
+	// OnBatch, if set, is called after each batch is executed with the number
+	// of rows in the batch and the rows affected by it.
+	OnBatch func(rows int, rowsAffected int64)
+}
+
+// InsertManyResult reports the rows affected by InsertMany.
+type InsertManyResult struct {
+	RowsAffected int64   // total rows affected by all batches
+	Batches      []int64 // rows affected by each batch, in order
+}
+
+// InsertMany inserts all rows into table with multi-row
+// INSERT ... VALUES (...), (...) statements. Columns are chosen from the
+// fields of T as described in Insert, but auto fields are not written back.
+// T is a struct or a pointer to a struct.
+//
+// rows are consumed as they are yielded and only one batch is held in
+// memory, so the Scanner returned by Select can be passed directly to copy
+// from one table to another. When doing so, the source query and the
+// inserts must not share a connection (e.g. the same Tx), as most drivers
+// don't allow a statement to be executed while rows are being read.
+// Use FromSlice or FromSeq to insert from a slice or an iter.Seq.
+//
+// If rows yields an error or a batch fails, the rows affected by the
+// previous batches are returned with the error.
+func InsertMany[T any](ctx context.Context, e Execer, table string, rows Scanner[T], opts *InsertManyOptions) (InsertManyResult, error) {
+	var res InsertManyResult
+	db := dbOf(e)
+	if opts == nil {
+		opts = &InsertManyOptions{}
+	}
+	cols, _, err := insertColumns(db.mapperOrDefault(), derefType(reflect.TypeFor[T]()))
+	if err != nil {
+		return res, db.handleErr(err)
+	}
+
+	d := db.dialectOrDefault()
+	maxRows := opts.MaxPlaceholders
+	if maxRows <= 0 {
+		maxRows = d.Capabilities().MaxPlaceholders
+	}
+	if maxRows = maxRows / len(cols); maxRows < 1 {
+		return res, db.handleErr(fmt.Errorf("%d columns exceed the placeholder limit", len(cols)))
+	}
+	if opts.BatchSize > 0 {
+		maxRows = min(maxRows, opts.BatchSize)
+	}
+	maxSize := opts.MaxPacketSize
+	if maxSize <= 0 {
+		maxSize = DefaultMaxPacketSize
+	}
+	// the size of the statement without any rows
+	baseSize := len(insertQuery(d, table, cols, 0))
+	// the size of the placeholders of one row, e.g. "(?, ?), "
+	rowSize := 3*len(cols) + 2
+
+	var (
+		args  []any
+		n     int
+		size  = baseSize
+		flush = func() error {
+			if n == 0 {
+				return nil
+			}
+			r, err := exec(ctx, e, insertQuery(d, table, cols, n), args, false)
+			if err != nil {
+				return fmt.Errorf("batch %d: %w", len(res.Batches)+1, err)
+			}
+			affected, err := r.RowsAffected()
+			if err != nil {
+				return db.handleErr(err)
+			}
+			res.RowsAffected += affected
+			res.Batches = append(res.Batches, affected)
+			if opts.OnBatch != nil {
+				opts.OnBatch(n, affected)
+			}
+			args, n, size = args[:0], 0, baseSize
+			return nil
+		}
+	)
+
+	for row, err := range rows {
+		if err != nil {
+			return res, err
+		}
+		v := reflect.Indirect(reflect.ValueOf(row))
+		if !v.IsValid() {
+			return res, db.handleErr(fmt.Errorf("cannot insert nil %T", row))
+		}
+		start := len(args)
+		if args, err = columnValues(v, cols, args); err != nil {
+			return res, db.handleErr(err)
+		}
+		s := rowSize
+		for _, arg := range args[start:] {
+			s += argSize(arg)
+		}
+		// send the rows so far if this row doesn't fit in the batch
+		if n > 0 && size+s > maxSize {
+			rowArgs := slices.Clone(args[start:])
+			args = args[:start]
+			if err := flush(); err != nil {
+				return res, err
+			}
+			args = append(args, rowArgs...)
+		}
+		n++
+		size += s
+		if n == maxRows {
+			if err := flush(); err != nil {
+				return res, err
+			}
+		}
+	}
+	return res, flush()
+}
+
+// argSize estimates the number of bytes used to send arg to the database.
+func argSize(arg any) int {
+	switch arg := arg.(type) {
+	case string:
+		return len(arg)
+	case []byte:
+		return len(arg)
+	case nil:
+		return 4
+	default:
+		return 8
+	}
+}
+
+// insertColumns returns the fields of t that are inserted as columns, in
+// declaration order, and the auto-increment field if there is one.
+func insertColumns(m *Mapper, t reflect.Type) (cols []*FieldInfo, auto *FieldInfo, err error) {
+	if t.Kind() != reflect.Struct {
+		return nil, nil, fmt.Errorf("insert type must be a struct, got %s", t)
+	}
+	for _, fi := range columnFields(m, t) {
+		switch {
+		case fi.HasOption("auto"):
+			if auto != nil {
+				return nil, nil, fmt.Errorf("multiple auto fields in %s: %s and %s", t, auto.Name, fi.Name)
+			}
+			auto = fi
+		case fi.HasOption("readonly"):
+		default:
+			cols = append(cols, fi)
+		}
+	}
+	if len(cols) == 0 {
+		return nil, nil, fmt.Errorf("no insertable columns in %s", t)
+	}
+	return cols, auto, nil
+}
+
+// columnFields returns the fields of struct type t that map to columns of
+// the table of t, in declaration order. A field maps to a column if it is
+// not a field of a nested struct, and its type is scannable or implements
+// driver.Valuer.
+func columnFields(m *Mapper, t reflect.Type) []*FieldInfo {
+	tm := m.TypeMap(t)
+	fields := make([]*FieldInfo, 0, len(tm.Names))
+	for path, fi := range tm.Names {
+		if strings.Contains(path, ".") {
+			continue
+		}
+		ft := derefType(fi.Field.Type)
+		if !isScannable(m, ft) && !reflect.PointerTo(ft).Implements(_valuerInterface) {
+			continue
+		}
+		fields = append(fields, fi)
+	}
+	slices.SortFunc(fields, func(a, b *FieldInfo) int {
+		return slices.Compare(a.Traversal, b.Traversal)
+	})
+	return fields
+}
+
+// columnValues appends the values of cols in struct value v to args.
+func columnValues(v reflect.Value, cols []*FieldInfo, args []any) ([]any, error) {
+	for _, fi := range cols {
+		val, ok := fieldValue(v, fi.Traversal)
+		if !ok {
+			return nil, fmt.Errorf("cannot read column %s from unexported field of %s", fi.Name, v.Type())
+		}
+		args = append(args, val)
+	}
+	return args, nil
+}
+
+// insertQuery returns an INSERT statement for cols with placeholders for
+// the given number of rows.
+func insertQuery(d Dialect, table string, cols []*FieldInfo, rows int) string {
+	var b strings.Builder
+	b.WriteString("INSERT INTO ")
+	b.WriteString(d.Quote(table))
+	b.WriteString(" (")
+	for i, fi := range cols {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(d.Quote(fi.Name))
+	}
+	b.WriteString(") VALUES ")
+	for r := range rows {
+		if r > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteByte('(')
+		for i := range cols {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteByte('?')
+		}
+		b.WriteByte(')')
+	}
+	return b.String()
+}
+
+// setInt sets the integer field v to id.
+func setInt(v reflect.Value, id int64) error {
+	switch v.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if v.OverflowInt(id) {
+			return fmt.Errorf("value %d overflows %s", id, v.Type())
+		}
+		v.SetInt(id)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if id < 0 || v.OverflowUint(uint64(id)) {
+			return fmt.Errorf("value %d overflows %s", id, v.Type())
+		}
+		v.SetUint(uint64(id))
+	default:
+		return fmt.Errorf("auto field must be an integer, got %s", v.Type())
+	}
+	return nil
+}
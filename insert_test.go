@@ -0,0 +1,299 @@
+package dbx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type insertAudit struct {
+	CreatedAt time.Time `db:"created_at,readonly"`
+	UpdatedBy string    `db:"updated_by"`
+}
+
+type insertPerson struct {
+	ID int64 `db:"id,auto"`
+	Person
+	*insertAudit
+	Address struct {
+		City string `db:"city"`
+	} `db:"address"`
+	Nickname *string `db:"nickname"`
+	Ignored  string  `db:"-"`
+}
+
+func TestInsert(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, conn := newFakeDB(t, func(string, []driver.NamedValue) (fakeResult, error) {
+		return fakeResult{lastInsertID: 42, rowsAffected: 1}, nil
+	})
+
+	t.Run("MySQL", func(t *testing.T) {
+		p := insertPerson{Person: person(1), insertAudit: &insertAudit{UpdatedBy: "admin"}}
+		res, err := Insert(ctx, NewDB(sqlDB), "person", &p)
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		if n, _ := res.RowsAffected(); n != 1 {
+			t.Fatalf("got %d rows affected want 1", n)
+		}
+		wantQuery := "INSERT INTO `person` (`first_name`, `last_name`, `added_at`, `email`, `updated_by`, `nickname`) VALUES (?, ?, ?, ?, ?, ?)"
+		if got := conn.lastQuery(); got != wantQuery {
+			t.Fatalf("got %q want %q", got, wantQuery)
+		}
+		wantArgs := []any{"FirstName1", "LastName1", p.AddedAt, "1@domain.com", "admin", nil}
+		if got := conn.lastArgs(); !cmp.Equal(got, wantArgs) {
+			t.Fatalf("(-got +want) %s", cmp.Diff(got, wantArgs))
+		}
+		if p.ID != 42 {
+			t.Fatalf("got ID %d want 42", p.ID)
+		}
+	})
+	t.Run("Postgres", func(t *testing.T) {
+		p := insertPerson{Person: person(1)}
+		if _, err := Insert(ctx, NewDB(sqlDB, WithDialect(Postgres)), "public.person", &p); err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		wantQuery := `INSERT INTO "public"."person" ("first_name", "last_name", "added_at", "email", "updated_by", "nickname") VALUES ($1, $2, $3, $4, $5, $6)`
+		if got := conn.lastQuery(); got != wantQuery {
+			t.Fatalf("got %q want %q", got, wantQuery)
+		}
+		// the nil embedded pointer is inserted as NULL and not allocated
+		if got := conn.lastArgs(); got[4] != nil || p.insertAudit != nil {
+			t.Fatalf("got %v want nil updated_by", got[4])
+		}
+		// LastInsertId is not supported by Postgres
+		if p.ID != 0 {
+			t.Fatalf("got ID %d want 0", p.ID)
+		}
+	})
+	t.Run("plain Execer", func(t *testing.T) {
+		type place struct {
+			ID      uint8  `db:"id,auto"`
+			Country string `db:"country"`
+		}
+		p := place{Country: "Japan"}
+		if _, err := Insert(ctx, sqlDB, "place", &p); err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		if got, want := conn.lastQuery(), "INSERT INTO `place` (`country`) VALUES (?)"; got != want {
+			t.Fatalf("got %q want %q", got, want)
+		}
+		if p.ID != 42 {
+			t.Fatalf("got ID %d want 42", p.ID)
+		}
+	})
+	t.Run("errors", func(t *testing.T) {
+		n := len(conn.queries())
+		type twoAuto struct {
+			A int `db:"a,auto"`
+			B int `db:"b,auto"`
+			C int `db:"c"`
+		}
+		type onlyAuto struct {
+			A int `db:"a,auto"`
+		}
+		tests := map[string]func() error{
+			"nil":      func() error { _, err := Insert[insertPerson](ctx, sqlDB, "person", nil); return err },
+			"scalar":   func() error { v := 1; _, err := Insert(ctx, sqlDB, "person", &v); return err },
+			"two auto": func() error { _, err := Insert(ctx, sqlDB, "t", &twoAuto{}); return err },
+			"no cols":  func() error { _, err := Insert(ctx, sqlDB, "t", &onlyAuto{}); return err },
+		}
+		for name, fn := range tests {
+			if err := fn(); err == nil {
+				t.Errorf("%s: got nil want error", name)
+			}
+		}
+		if len(conn.queries()) != n {
+			t.Fatal("query was sent despite error")
+		}
+
+		type overflow struct {
+			ID   int8   `db:"id,auto"`
+			Name string `db:"name"`
+		}
+		bigDB, _ := newFakeDB(t, func(string, []driver.NamedValue) (fakeResult, error) {
+			return fakeResult{lastInsertID: 1000}, nil
+		})
+		_, err := Insert(ctx, bigDB, "t", &overflow{})
+		if err == nil || !strings.Contains(err.Error(), "overflows int8") {
+			t.Fatalf("got %+v want overflow error", err)
+		}
+	})
+}
+
+func TestInsertMany(t *testing.T) {
+	ctx := context.Background()
+	type row struct {
+		ID   int64  `db:"id,auto"`
+		Name string `db:"name"`
+		Age  int    `db:"age"`
+	}
+	newRows := func(n int) []row {
+		rows := make([]row, n)
+		for i := range rows {
+			rows[i] = row{Name: "name" + strings.Repeat("x", i%3), Age: i}
+		}
+		return rows
+	}
+
+	var batchArgs [][]any
+	sqlDB, conn := newFakeDB(t, func(query string, args []driver.NamedValue) (fakeResult, error) {
+		if strings.HasPrefix(query, "SELECT") {
+			return rowsOf([]string{"name", "age"},
+				[]driver.Value{"a", int64(1)},
+				[]driver.Value{"b", int64(2)},
+				[]driver.Value{"c", int64(3)},
+			), nil
+		}
+		var vals []any
+		for _, a := range args {
+			vals = append(vals, a.Value)
+		}
+		batchArgs = append(batchArgs, vals)
+		return fakeResult{rowsAffected: int64(len(args) / 2)}, nil
+	})
+	db := NewDB(sqlDB)
+	reset := func() {
+		batchArgs = nil
+		conn.mu.Lock()
+		conn.log = nil
+		conn.mu.Unlock()
+	}
+
+	tests := []struct {
+		name        string
+		rows        int
+		opts        *InsertManyOptions
+		wantBatches []int64
+	}{
+		{name: "single batch", rows: 5, opts: nil, wantBatches: []int64{5}},
+		{name: "batch size", rows: 5, opts: &InsertManyOptions{BatchSize: 2}, wantBatches: []int64{2, 2, 1}},
+		{name: "placeholders", rows: 7, opts: &InsertManyOptions{MaxPlaceholders: 7}, wantBatches: []int64{3, 3, 1}},
+		{name: "exact batches", rows: 4, opts: &InsertManyOptions{BatchSize: 2}, wantBatches: []int64{2, 2}},
+		// the base statement is 44 bytes, and each row is 8 bytes of placeholders and 12-14 bytes of args
+		{name: "packet size", rows: 5, opts: &InsertManyOptions{MaxPacketSize: 100}, wantBatches: []int64{2, 2, 1}},
+		{name: "row larger than packet", rows: 2, opts: &InsertManyOptions{MaxPacketSize: 1}, wantBatches: []int64{1, 1}},
+		{name: "no rows", rows: 0, opts: nil, wantBatches: nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			reset()
+			rows := newRows(tt.rows)
+			var onBatch []int
+			opts := tt.opts
+			if opts != nil {
+				opts.OnBatch = func(n int, _ int64) { onBatch = append(onBatch, n) }
+			}
+			res, err := InsertMany(ctx, db, "person", FromSlice(rows), opts)
+			if err != nil {
+				t.Fatalf("got %+v want nil", err)
+			}
+			if !cmp.Equal(res.Batches, tt.wantBatches) {
+				t.Fatalf("(-got +want) %s", cmp.Diff(res.Batches, tt.wantBatches))
+			}
+			if res.RowsAffected != int64(tt.rows) {
+				t.Fatalf("got %d rows affected want %d", res.RowsAffected, tt.rows)
+			}
+			if opts != nil && len(onBatch) != len(tt.wantBatches) {
+				t.Fatalf("got %d OnBatch calls want %d", len(onBatch), len(tt.wantBatches))
+			}
+			// all rows are sent in order
+			var got []any
+			for _, args := range batchArgs {
+				got = append(got, args...)
+			}
+			var want []any
+			for _, r := range rows {
+				want = append(want, r.Name, int64(r.Age))
+			}
+			if !cmp.Equal(got, want) {
+				t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+			}
+		})
+	}
+
+	t.Run("statement", func(t *testing.T) {
+		reset()
+		if _, err := InsertMany(ctx, NewDB(sqlDB, WithDialect(Postgres)), "person", FromSlice(newRows(2)), nil); err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		want := []string{`INSERT INTO "person" ("name", "age") VALUES ($1, $2), ($3, $4)`}
+		if got := conn.queries(); !cmp.Equal(got, want) {
+			t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+		}
+	})
+	t.Run("pointers from iter.Seq", func(t *testing.T) {
+		reset()
+		seq := func(yield func(*row) bool) {
+			for _, r := range newRows(3) {
+				if !yield(&r) {
+					return
+				}
+			}
+		}
+		res, err := InsertMany(ctx, db, "person", FromSeq(seq), nil)
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		if res.RowsAffected != 3 {
+			t.Fatalf("got %d rows affected want 3", res.RowsAffected)
+		}
+	})
+	t.Run("stream from Scanner", func(t *testing.T) {
+		reset()
+		src := Select[row](ctx, db, "SELECT name, age FROM person")
+		res, err := InsertMany(ctx, db, "person_copy", src, &InsertManyOptions{BatchSize: 2})
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		if want := []int64{2, 1}; !cmp.Equal(res.Batches, want) {
+			t.Fatalf("(-got +want) %s", cmp.Diff(res.Batches, want))
+		}
+		// the first batch is sent before the source is fully read
+		want := []string{
+			"SELECT name, age FROM person",
+			"INSERT INTO `person_copy` (`name`, `age`) VALUES (?, ?), (?, ?)",
+			"INSERT INTO `person_copy` (`name`, `age`) VALUES (?, ?)",
+		}
+		if got := conn.queries(); !cmp.Equal(got, want) {
+			t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+		}
+	})
+	t.Run("source error", func(t *testing.T) {
+		reset()
+		errSource := errors.New("source failed")
+		src := func(yield func(row, error) bool) {
+			for _, r := range newRows(3) {
+				if !yield(r, nil) {
+					return
+				}
+			}
+			yield(row{}, errSource)
+		}
+		res, err := InsertMany(ctx, db, "person", src, &InsertManyOptions{BatchSize: 2})
+		if !errors.Is(err, errSource) {
+			t.Fatalf("got %+v want %+v", err, errSource)
+		}
+		if res.RowsAffected != 2 {
+			t.Fatalf("got %d rows affected want 2", res.RowsAffected)
+		}
+	})
+	t.Run("too many columns", func(t *testing.T) {
+		_, err := InsertMany(ctx, db, "person", FromSlice(newRows(1)), &InsertManyOptions{MaxPlaceholders: 1})
+		if err == nil || !strings.Contains(err.Error(), "placeholder limit") {
+			t.Fatalf("got %+v want placeholder limit error", err)
+		}
+	})
+	t.Run("nil row", func(t *testing.T) {
+		_, err := InsertMany(ctx, db, "person", FromSlice([]*row{nil}), nil)
+		if err == nil || !strings.Contains(err.Error(), "cannot insert nil") {
+			t.Fatalf("got %+v want nil row error", err)
+		}
+	})
+}
@@ -3,6 +3,7 @@ package dbx
 import (
 	"reflect"
 	"slices"
+	"strings"
 	"sync"
 )
 
@@ -16,11 +17,18 @@ type FieldInfo struct {
 	Field     reflect.StructField
 	Zero      reflect.Value
 	Name      string
+	Options   map[string]string // options following the name in the tag, e.g. "auto" in `db:"id,auto"`
 	Embedded  bool
 	Children  []*FieldInfo
 	Parent    *FieldInfo
 }
 
+// HasOption reports whether the field's tag has the given option.
+func (f *FieldInfo) HasOption(name string) bool {
+	_, ok := f.Options[name]
+	return ok
+}
+
 func (f *FieldInfo) IsRecursive() bool {
 	for p := f.Parent; p != nil; p = p.Parent {
 		if f.Field.Type == p.Field.Type {
@@ -51,6 +59,10 @@ type Mapper struct {
 	tagMapFunc func(string) string // called on the whole tag (could be used to e.g. ignore omitempty -> return "" to ignore)
 	mapFunc    func(string) string // maps field names to column names. Used when tag not available. Tag split from options (comma sep) by default
 	mutex      sync.Mutex
+	plans      sync.Map // *scanPlan by planKey
+	// dbxgenRules is true if names are mapped with the db tag and
+	// strings.ToLower, like DefaultMapper and the code generated by dbxgen
+	dbxgenRules bool
 }
 
 // NewMapperFunc returns a new mapper which optionally obeys a field tag and
@@ -58,9 +70,10 @@ type Mapper struct {
 // for any other field, the mapped name will be f(field.Name)
 func NewMapperFunc(tagName string, f func(string) string) *Mapper {
 	return &Mapper{
-		cache:   make(map[reflect.Type]*StructMap),
-		tagName: tagName,
-		mapFunc: f,
+		cache:       make(map[reflect.Type]*StructMap),
+		tagName:     tagName,
+		mapFunc:     f,
+		dbxgenRules: tagName == "db" && f != nil && reflect.ValueOf(f).Pointer() == reflect.ValueOf(strings.ToLower).Pointer(),
 	}
 }
 
@@ -160,9 +173,10 @@ func getMapping(t reflect.Type, tagName string, mapFunc, tagMapFunc mapf) *Struc
 			}
 
 			fi := FieldInfo{
-				Field: f,
-				Name:  name,
-				Zero:  reflect.New(f.Type).Elem(),
+				Field:   f,
+				Name:    name,
+				Options: parseOptions(tag),
+				Zero:    reflect.New(f.Type).Elem(),
 			}
 
 			// if the path is empty this path is just the name
@@ -0,0 +1,37 @@
+package dbx
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestTypeMapOptions(t *testing.T) {
+	type tagged struct {
+		ID        int64  `db:"id,auto"`
+		Name      string `db:"name"`
+		CreatedAt string `db:"created_at, readonly ,format=rfc3339"`
+		Untagged  string
+	}
+	tm := DefaultMapper.TypeMap(reflect.TypeFor[tagged]())
+
+	tests := map[string]map[string]string{
+		"id":         {"auto": ""},
+		"name":       nil,
+		"created_at": {"readonly": "", "format": "rfc3339"},
+		"untagged":   nil,
+	}
+	for name, want := range tests {
+		fi, ok := tm.Names[name]
+		if !ok {
+			t.Fatalf("missing field %s", name)
+		}
+		if !cmp.Equal(fi.Options, want) {
+			t.Errorf("%s: (-got +want) %s", name, cmp.Diff(fi.Options, want))
+		}
+	}
+	if !tm.Names["id"].HasOption("auto") || tm.Names["name"].HasOption("auto") {
+		t.Fatal("HasOption returned wrong result")
+	}
+}
@@ -0,0 +1,260 @@
+package dbx
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// DefaultBuckets are the upper bounds of the latency histograms of Metrics
+// created without buckets.
+var DefaultBuckets = []time.Duration{
+	time.Millisecond,
+	5 * time.Millisecond,
+	10 * time.Millisecond,
+	25 * time.Millisecond,
+	50 * time.Millisecond,
+	100 * time.Millisecond,
+	250 * time.Millisecond,
+	500 * time.Millisecond,
+	time.Second,
+	2500 * time.Millisecond,
+	5 * time.Second,
+	10 * time.Second,
+}
+
+// Metrics is a Hook that collects metrics for each query fingerprint: a
+// latency histogram, the number of errors and rows scanned, and the number
+// of queries in flight. Queries are grouped by FingerprintFor and the
+// Dialect of the DB, so the metrics of a query don't depend on its literals
+// or the length of its IN lists, and by the name set with WithQueryName, if
+// any.
+type Metrics struct {
+	buckets []time.Duration
+
+	mu      sync.RWMutex
+	queries map[statsKey]*queryStats
+}
+
+type statsKey struct{ name, fingerprint string }
+
+// queryStats are the metrics of a name and fingerprint.
+type queryStats struct {
+	query    string // normalized
+	count    atomic.Int64
+	errors   atomic.Int64
+	rows     atomic.Int64
+	inFlight atomic.Int64
+	sum      atomic.Int64   // nanoseconds
+	buckets  []atomic.Int64 // not cumulative
+}
+
+var _ Hook = (*Metrics)(nil)
+
+// NewMetrics returns Metrics with latency histograms with the given bucket
+// upper bounds, or DefaultBuckets if none are given. Register them with
+// WithMetrics.
+func NewMetrics(buckets ...time.Duration) *Metrics {
+	if len(buckets) == 0 {
+		buckets = DefaultBuckets
+	}
+	buckets = slices.Clone(buckets)
+	slices.Sort(buckets)
+	return &Metrics{
+		buckets: slices.Compact(buckets),
+		queries: make(map[statsKey]*queryStats),
+	}
+}
+
+// WithMetrics makes the DB collect m. It is the same as WithHooks(m).
+func WithMetrics(m *Metrics) Option {
+	return WithHooks(m)
+}
+
+type metricsKey struct{ m *Metrics }
+
+func (m *Metrics) BeforeQuery(ctx context.Context, info QueryInfo) context.Context {
+	s := m.stats(info.Name, info.Dialect, info.Query)
+	s.inFlight.Add(1)
+	return context.WithValue(ctx, metricsKey{m}, s)
+}
+
+func (m *Metrics) AfterQuery(ctx context.Context, info QueryInfo, rowsScanned int, err error) {
+	s, ok := ctx.Value(metricsKey{m}).(*queryStats)
+	if !ok {
+		return
+	}
+	s.inFlight.Add(-1)
+	s.count.Add(1)
+	s.rows.Add(int64(rowsScanned))
+	s.sum.Add(int64(info.Duration))
+	if err != nil {
+		s.errors.Add(1)
+	}
+	if i, _ := slices.BinarySearch(m.buckets, info.Duration); i < len(m.buckets) {
+		s.buckets[i].Add(1)
+	}
+}
+
+// stats returns the stats of the name and the fingerprint of query in the
+// dialect d, creating them if needed.
+func (m *Metrics) stats(name string, d Dialect, query string) *queryStats {
+	normalized := NormalizeQueryFor(d, query)
+	key := statsKey{name, fingerprint(normalized)}
+	m.mu.RLock()
+	s, ok := m.queries[key]
+	m.mu.RUnlock()
+	if ok {
+		return s
+	}
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if s, ok := m.queries[key]; ok {
+		return s
+	}
+	s = &queryStats{query: normalized, buckets: make([]atomic.Int64, len(m.buckets))}
+	m.queries[key] = s
+	return s
+}
+
+// QueryMetrics is a snapshot of the metrics of a query fingerprint.
+type QueryMetrics struct {
+	Name        string // set with WithQueryName; empty for unnamed queries
+	Fingerprint string
+	Query       string // normalized with NormalizeQueryFor
+	Count       int64  // completed queries
+	Errors      int64
+	Rows        int64 // rows scanned
+	InFlight    int64
+	Sum         time.Duration // total latency of completed queries
+	Buckets     []Bucket
+}
+
+// Bucket is a bucket of a latency histogram.
+type Bucket struct {
+	UpperBound time.Duration
+	Count      int64 // queries with a latency of at most UpperBound
+}
+
+// Snapshot returns the current metrics, ordered by name and fingerprint.
+func (m *Metrics) Snapshot() []QueryMetrics {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	snapshot := make([]QueryMetrics, 0, len(m.queries))
+	for key, s := range m.queries {
+		qm := QueryMetrics{
+			Name:        key.name,
+			Fingerprint: key.fingerprint,
+			Query:       s.query,
+			Count:       s.count.Load(),
+			Errors:      s.errors.Load(),
+			Rows:        s.rows.Load(),
+			InFlight:    s.inFlight.Load(),
+			Sum:         time.Duration(s.sum.Load()),
+			Buckets:     make([]Bucket, len(m.buckets)),
+		}
+		var n int64
+		for i, b := range m.buckets {
+			n += s.buckets[i].Load()
+			qm.Buckets[i] = Bucket{UpperBound: b, Count: n}
+		}
+		snapshot = append(snapshot, qm)
+	}
+	slices.SortFunc(snapshot, func(a, b QueryMetrics) int {
+		if c := strings.Compare(a.Name, b.Name); c != 0 {
+			return c
+		}
+		return strings.Compare(a.Fingerprint, b.Fingerprint)
+	})
+	return snapshot
+}
+
+// A MetricsExporter exports snapshots of Metrics, e.g. to a monitoring
+// system.
+type MetricsExporter interface {
+	Export(ctx context.Context, metrics []QueryMetrics) error
+}
+
+// Export exports a Snapshot of m with e.
+func (m *Metrics) Export(ctx context.Context, e MetricsExporter) error {
+	return e.Export(ctx, m.Snapshot())
+}
+
+// PrometheusExporter is a MetricsExporter that writes metrics to W in the
+// Prometheus text exposition format. Each metric has the fingerprint and
+// query labels, and the name label for named queries:
+//
+//	<namespace>_query_duration_seconds  histogram of latencies
+//	<namespace>_query_errors_total      counter of errors
+//	<namespace>_query_rows_total        counter of rows scanned
+//	<namespace>_queries_in_flight       gauge of running queries
+type PrometheusExporter struct {
+	W         io.Writer
+	Namespace string // "dbx" if empty
+}
+
+func (e *PrometheusExporter) Export(_ context.Context, metrics []QueryMetrics) error {
+	ns := e.Namespace
+	if ns == "" {
+		ns = "dbx"
+	}
+	var b strings.Builder
+	header := func(name, typ, help string) {
+		fmt.Fprintf(&b, "# HELP %s_%s %s\n# TYPE %s_%s %s\n", ns, name, help, ns, name, typ)
+	}
+
+	header("query_duration_seconds", "histogram", "Latency of queries by fingerprint.")
+	for _, qm := range metrics {
+		labels := promLabels(qm)
+		for _, bk := range qm.Buckets {
+			fmt.Fprintf(&b, "%s_query_duration_seconds_bucket{%s,le=%q} %d\n", ns, labels, promFloat(bk.UpperBound.Seconds()), bk.Count)
+		}
+		fmt.Fprintf(&b, "%s_query_duration_seconds_bucket{%s,le=\"+Inf\"} %d\n", ns, labels, qm.Count)
+		fmt.Fprintf(&b, "%s_query_duration_seconds_sum{%s} %s\n", ns, labels, promFloat(qm.Sum.Seconds()))
+		fmt.Fprintf(&b, "%s_query_duration_seconds_count{%s} %d\n", ns, labels, qm.Count)
+	}
+	for _, c := range []struct {
+		name, typ, help string
+		value           func(QueryMetrics) int64
+	}{
+		{"query_errors_total", "counter", "Errors of queries by fingerprint.", func(qm QueryMetrics) int64 { return qm.Errors }},
+		{"query_rows_total", "counter", "Rows scanned by queries by fingerprint.", func(qm QueryMetrics) int64 { return qm.Rows }},
+		{"queries_in_flight", "gauge", "Running queries by fingerprint.", func(qm QueryMetrics) int64 { return qm.InFlight }},
+	} {
+		header(c.name, c.typ, c.help)
+		for _, qm := range metrics {
+			fmt.Fprintf(&b, "%s_%s{%s} %d\n", ns, c.name, promLabels(qm), c.value(qm))
+		}
+	}
+	_, err := io.WriteString(e.W, b.String())
+	return err
+}
+
+// PrometheusHandler returns an http.Handler that serves m in the
+// Prometheus text exposition format, as written by PrometheusExporter.
+func PrometheusHandler(m *Metrics, namespace string) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+		_ = m.Export(r.Context(), &PrometheusExporter{W: w, Namespace: namespace})
+	})
+}
+
+var promEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`)
+
+func promLabels(qm QueryMetrics) string {
+	if qm.Name != "" {
+		return `name="` + promEscaper.Replace(qm.Name) + `",fingerprint="` + qm.Fingerprint + `",query="` + promEscaper.Replace(qm.Query) + `"`
+	}
+	return `fingerprint="` + qm.Fingerprint + `",query="` + promEscaper.Replace(qm.Query) + `"`
+}
+
+func promFloat(f float64) string {
+	return strconv.FormatFloat(f, 'g', -1, 64)
+}
@@ -0,0 +1,119 @@
+package dbx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/google/go-cmp/cmp/cmpopts"
+)
+
+func TestMetrics(t *testing.T) {
+	ctx := context.Background()
+	m := NewMetrics(time.Hour, time.Hour, 0)
+	errFail := errors.New("fail")
+	db, _ := txDB(t, map[string]error{
+		"UPDATE person SET age = 1 WHERE id = ?":  errFail,
+		"UPDATE person SET age = 20 WHERE id = ?": errFail,
+	}, WithMetrics(m))
+
+	// IN lists of any length have the same fingerprint
+	for _, ids := range [][]int{{1}, {1, 2, 3}} {
+		if _, err := Select[string](ctx, db, "SELECT first_name FROM person WHERE id IN (?)", ids).Collect(); err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+	}
+	// so do literals
+	for _, age := range []string{"1", "20"} {
+		_, _ = Exec(ctx, db, "UPDATE person SET age = "+age+" WHERE id = ?", 1)
+	}
+	// and queries in flight are counted until iteration ends
+	for range Select[string](ctx, db, "SELECT first_name FROM person WHERE id = 1") {
+		break
+	}
+	for range Select[string](ctx, db, "SELECT first_name FROM person WHERE id = 2") {
+		for _, qm := range m.Snapshot() {
+			if want := int64(strings.Count(qm.Query, "SELECT first_name FROM person WHERE id = ?")); qm.InFlight != want {
+				t.Fatalf("got %d want %d queries in flight for %s", qm.InFlight, want, qm.Query)
+			}
+		}
+	}
+
+	want := []QueryMetrics{
+		{Query: "SELECT first_name FROM person WHERE id IN (?)", Count: 2, Rows: 4},
+		{Query: "SELECT first_name FROM person WHERE id = ?", Count: 2, Rows: 3},
+		{Query: "UPDATE person SET age = ? WHERE id = ?", Count: 2, Errors: 2},
+	}
+	for i := range want {
+		want[i].Fingerprint = Fingerprint(want[i].Query)
+		want[i].Buckets = []Bucket{{UpperBound: 0}, {UpperBound: time.Hour, Count: want[i].Count}}
+	}
+	got := m.Snapshot()
+	opts := []cmp.Option{
+		cmpopts.IgnoreFields(QueryMetrics{}, "Sum"),
+		cmpopts.SortSlices(func(a, b QueryMetrics) bool { return a.Query < b.Query }),
+	}
+	if diff := cmp.Diff(got, want, opts...); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+	for _, qm := range got {
+		if qm.Sum <= 0 && qm.Count > 0 {
+			t.Errorf("got sum %s want > 0 for %s", qm.Sum, qm.Query)
+		}
+	}
+}
+
+func TestPrometheusExporter(t *testing.T) {
+	ctx := context.Background()
+	m := NewMetrics(time.Hour)
+	// "a\b" is an identifier in Postgres, so it is kept and escaped
+	db, _ := txDB(t, nil, WithDialect(Postgres), WithMetrics(m))
+	if _, err := Get[string](ctx, db, "SELECT first_name FROM person WHERE name = \"a\\b\""); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	fp := FingerprintFor(Postgres, `SELECT first_name FROM person WHERE name = "a\b"`)
+
+	var buf bytes.Buffer
+	if err := m.Export(ctx, &PrometheusExporter{W: &buf, Namespace: "app"}); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	labels := `{fingerprint="` + fp + `",query="SELECT first_name FROM person WHERE name = \"a\\b\""`
+	want := []string{
+		"# HELP app_query_duration_seconds Latency of queries by fingerprint.",
+		"# TYPE app_query_duration_seconds histogram",
+		`app_query_duration_seconds_bucket` + labels + `,le="3600"} 1`,
+		`app_query_duration_seconds_bucket` + labels + `,le="+Inf"} 1`,
+		`app_query_duration_seconds_sum` + labels + `} <sum>`,
+		`app_query_duration_seconds_count` + labels + `} 1`,
+		"# HELP app_query_errors_total Errors of queries by fingerprint.",
+		"# TYPE app_query_errors_total counter",
+		`app_query_errors_total` + labels + `} 0`,
+		"# HELP app_query_rows_total Rows scanned by queries by fingerprint.",
+		"# TYPE app_query_rows_total counter",
+		`app_query_rows_total` + labels + `} 1`,
+		"# HELP app_queries_in_flight Running queries by fingerprint.",
+		"# TYPE app_queries_in_flight gauge",
+		`app_queries_in_flight` + labels + `} 0`,
+	}
+	got := strings.Split(strings.TrimSpace(buf.String()), "\n")
+	// the sum is not deterministic
+	for i, line := range got {
+		if strings.HasPrefix(line, "app_query_duration_seconds_sum") {
+			got[i] = line[:strings.LastIndexByte(line, ' ')] + " <sum>"
+		}
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+
+	rec := httptest.NewRecorder()
+	PrometheusHandler(m, "").ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	if !strings.Contains(rec.Body.String(), "dbx_query_rows_total"+labels+"} 1") {
+		t.Fatalf("got %s want dbx namespace", rec.Body.String())
+	}
+}
@@ -0,0 +1,219 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+)
+
+// NamedSelect is like Select, but binds :name style parameters in query
+// from arg. arg is a struct, a pointer to a struct or a map with string
+// keys. Struct fields are resolved by name with the Mapper of q, so the
+// same `db` tags used to scan a struct can be used to bind it.
+// Bound slice values are expanded as described in In.
+func NamedSelect[T any](ctx context.Context, q Queryer, query string, arg any) Scanner[T] {
+	db := dbOf(q)
+	bound, args, err := bindNamed(db.mapperOrDefault(), quotingOf(db.dialectOrDefault()), query, arg)
+	if err != nil {
+		return func(yield func(T, error) bool) {
+			var t T
+			yield(t, db.queryErr(err, query, nil, nil, -1))
+		}
+	}
+	return Select[T](ctx, q, bound, args...)
+}
+
+// NamedGet is like Get, but binds :name style parameters in query from arg.
+// See NamedSelect for the supported types of arg.
+func NamedGet[T any](ctx context.Context, q Queryer, query string, arg any) (T, error) {
+	db := dbOf(q)
+	bound, args, err := bindNamed(db.mapperOrDefault(), quotingOf(db.dialectOrDefault()), query, arg)
+	if err != nil {
+		var t T
+		return t, db.queryErr(err, query, nil, nil, -1)
+	}
+	return Get[T](ctx, q, bound, args...)
+}
+
+// NamedExec binds :name style parameters in query from arg and executes it.
+// See NamedSelect for the supported types of arg.
+func NamedExec(ctx context.Context, e Execer, query string, arg any) (sql.Result, error) {
+	db := dbOf(e)
+	bound, args, err := bindNamed(db.mapperOrDefault(), quotingOf(db.dialectOrDefault()), query, arg)
+	if err != nil {
+		return nil, db.queryErr(err, query, nil, nil, -1)
+	}
+	return Exec(ctx, e, bound, args...)
+}
+
+// namedQuery is a query compiled from :name style parameters to ? placeholders.
+type namedQuery struct {
+	query string   // query with each parameter replaced by ?
+	names []string // parameter names in order of appearance
+}
+
+// namedKey identifies a compiled query in namedCache.
+type namedKey struct {
+	q     quoting
+	query string
+}
+
+// namedCache holds compiled queries keyed by the original query string.
+// Queries are expected to be constants, so the cache is not bounded.
+var namedCache sync.Map // map[namedKey]*namedQuery
+
+// compileNamed returns the compiled form of query, compiling and caching it
+// on first use.
+func compileNamed(q quoting, query string) *namedQuery {
+	key := namedKey{q: q, query: query}
+	if nq, ok := namedCache.Load(key); ok {
+		return nq.(*namedQuery)
+	}
+	nq, _ := namedCache.LoadOrStore(key, parseNamed(q, query))
+	return nq.(*namedQuery)
+}
+
+// parseNamed replaces every :name parameter in query with ?.
+// Quoted strings, quoted identifiers and comments are copied verbatim,
+// as are :: casts and a : that is not followed by a name (e.g. :=).
+func parseNamed(q quoting, query string) *namedQuery {
+	var b strings.Builder
+	b.Grow(len(query))
+	nq := &namedQuery{}
+
+	for i := 0; i < len(query); {
+		if end := skipLiteral(q, query, i); end > i {
+			b.WriteString(query[i:end])
+			i = end
+			continue
+		}
+		c := query[i]
+		if c != ':' {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		if i+1 < len(query) && query[i+1] == ':' {
+			b.WriteString("::")
+			i += 2
+			continue
+		}
+		end := i + 1
+		for end < len(query) && isNameByte(query[end], end == i+1) {
+			end++
+		}
+		if end == i+1 {
+			b.WriteByte(c)
+			i++
+			continue
+		}
+		nq.names = append(nq.names, query[i+1:end])
+		b.WriteByte('?')
+		i = end
+	}
+
+	nq.query = b.String()
+	return nq
+}
+
+// isNameByte reports whether c can be part of a parameter name.
+// Names start with a letter or underscore, and may contain digits and dots
+// after that. Dots address fields of nested structs, e.g. :address.city.
+func isNameByte(c byte, first bool) bool {
+	switch {
+	case c == '_', 'a' <= c && c <= 'z', 'A' <= c && c <= 'Z':
+		return true
+	case '0' <= c && c <= '9', c == '.':
+		return !first
+	}
+	return false
+}
+
+// bindNamed compiles query and returns it with the args for each parameter
+// taken from arg.
+func bindNamed(m *Mapper, q quoting, query string, arg any) (string, []any, error) {
+	nq := compileNamed(q, query)
+	args, err := bindArgs(m, nq.names, arg)
+	if err != nil {
+		return "", nil, err
+	}
+	return nq.query, args, nil
+}
+
+// bindArgs returns the values of names in arg, which is a map with string
+// keys, a struct or a pointer to a struct.
+func bindArgs(m *Mapper, names []string, arg any) ([]any, error) {
+	args := make([]any, len(names))
+	if len(names) == 0 {
+		return args, nil
+	}
+
+	if vals, ok := arg.(map[string]any); ok {
+		for i, name := range names {
+			val, ok := vals[name]
+			if !ok {
+				return nil, fmt.Errorf("could not find name %s in %T", name, arg)
+			}
+			args[i] = val
+		}
+		return args, nil
+	}
+
+	v := reflect.ValueOf(arg)
+	for v.Kind() == reflect.Ptr {
+		if v.IsNil() {
+			return nil, fmt.Errorf("named parameters bound from nil %T", arg)
+		}
+		v = v.Elem()
+	}
+
+	switch {
+	case v.Kind() == reflect.Map && v.Type().Key().Kind() == reflect.String:
+		for i, name := range names {
+			val := v.MapIndex(reflect.ValueOf(name).Convert(v.Type().Key()))
+			if !val.IsValid() {
+				return nil, fmt.Errorf("could not find name %s in %T", name, arg)
+			}
+			args[i] = val.Interface()
+		}
+	case v.Kind() == reflect.Struct:
+		tm := m.TypeMap(v.Type())
+		for i, name := range names {
+			fi, ok := tm.Names[name]
+			if !ok {
+				return nil, fmt.Errorf("could not find name %s in %T", name, arg)
+			}
+			val, ok := fieldValue(v, fi.Traversal)
+			if !ok {
+				return nil, fmt.Errorf("cannot bind name %s from unexported field of %T", name, arg)
+			}
+			args[i] = val
+		}
+	default:
+		return nil, fmt.Errorf("named parameters must be bound from a struct or map, got %T", arg)
+	}
+	return args, nil
+}
+
+// fieldValue returns the interface value of the field given by traversal.
+// Unlike fieldByIndexes it never allocates: if a nil pointer is found on the
+// way to the field, nil is returned. ok is false if the field is reached
+// through an unexported embedded struct and cannot be read.
+func fieldValue(v reflect.Value, traversal []int) (val any, ok bool) {
+	for _, i := range traversal {
+		if v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil, true
+			}
+			v = v.Elem()
+		}
+		v = v.Field(i)
+	}
+	if !v.CanInterface() {
+		return nil, false
+	}
+	return v.Interface(), true
+}
@@ -0,0 +1,303 @@
+package dbx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestParseNamed(t *testing.T) {
+	tests := []struct {
+		name      string
+		dialect   Dialect // MySQL if nil
+		query     string
+		wantQuery string
+		wantNames []string
+	}{
+		{
+			name:      "no parameters",
+			query:     "SELECT * FROM person",
+			wantQuery: "SELECT * FROM person",
+		},
+		{
+			name:      "parameters",
+			query:     "SELECT * FROM person WHERE first_name = :first_name AND last_name = :last_name",
+			wantQuery: "SELECT * FROM person WHERE first_name = ? AND last_name = ?",
+			wantNames: []string{"first_name", "last_name"},
+		},
+		{
+			name:      "repeated parameter",
+			query:     "INSERT INTO t (a, b) VALUES (:a, :a)",
+			wantQuery: "INSERT INTO t (a, b) VALUES (?, ?)",
+			wantNames: []string{"a", "a"},
+		},
+		{
+			name:      "nested name",
+			query:     "SELECT * FROM place WHERE city = :address.city1",
+			wantQuery: "SELECT * FROM place WHERE city = ?",
+			wantNames: []string{"address.city1"},
+		},
+		{
+			name:      "quoted strings and identifiers",
+			query:     `SELECT ':a', ":b", ` + "`:c`" + `, 'it''s :d', 'x\':e' FROM t WHERE f = :f`,
+			wantQuery: `SELECT ':a', ":b", ` + "`:c`" + `, 'it''s :d', 'x\':e' FROM t WHERE f = ?`,
+			wantNames: []string{"f"},
+		},
+		{
+			name:      "comments",
+			query:     "SELECT a -- :b\nFROM t /* :c */ WHERE d = :d",
+			wantQuery: "SELECT a -- :b\nFROM t /* :c */ WHERE d = ?",
+			wantNames: []string{"d"},
+		},
+		{
+			name:      "hash comments",
+			query:     "UPDATE t SET a = :a # :b\nWHERE c = :c #",
+			wantQuery: "UPDATE t SET a = ? # :b\nWHERE c = ? #",
+			wantNames: []string{"a", "c"},
+		},
+		{
+			name:      "hash operator",
+			dialect:   Postgres,
+			query:     "SELECT :a # :b",
+			wantQuery: "SELECT ? # ?",
+			wantNames: []string{"a", "b"},
+		},
+		{
+			name:      "casts",
+			query:     "SELECT :id::bigint, created_at::date FROM t",
+			wantQuery: "SELECT ?::bigint, created_at::date FROM t",
+			wantNames: []string{"id"},
+		},
+		{
+			name:      "lone colons",
+			query:     "SET @x := 1, @y = ':' , @z = :z",
+			wantQuery: "SET @x := 1, @y = ':' , @z = ?",
+			wantNames: []string{"z"},
+		},
+		{
+			name:      "digit after colon",
+			query:     "SELECT '12:00', 12:30 FROM t",
+			wantQuery: "SELECT '12:00', 12:30 FROM t",
+		},
+		{
+			name:      "unterminated literal",
+			query:     "SELECT ':a",
+			wantQuery: "SELECT ':a",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			d := tt.dialect
+			if d == nil {
+				d = MySQL
+			}
+			nq := parseNamed(quotingOf(d), tt.query)
+			if nq.query != tt.wantQuery {
+				t.Fatalf("got %q want %q", nq.query, tt.wantQuery)
+			}
+			if !cmp.Equal(nq.names, tt.wantNames) {
+				t.Fatalf("(-got +want) %s", cmp.Diff(nq.names, tt.wantNames))
+			}
+		})
+	}
+}
+
+func TestCompileNamedCache(t *testing.T) {
+	const query = "SELECT * FROM person WHERE email = :email"
+	first := compileNamed(quotingOf(MySQL), query)
+	if second := compileNamed(quotingOf(MySQL), query); first != second {
+		t.Fatal("compiled query was not cached")
+	}
+}
+
+func TestBindArgs(t *testing.T) {
+	type address struct {
+		City string `db:"city"`
+	}
+	type named struct {
+		Person
+		Address *address `db:"address"`
+		ID      int64    `db:"id"`
+	}
+	p := named{Person: person(1), Address: &address{City: "Tokyo"}, ID: 10}
+
+	tests := []struct {
+		name    string
+		names   []string
+		arg     any
+		want    []any
+		wantErr string
+	}{
+		{
+			name:  "struct",
+			names: []string{"id", "first_name", "email", "address.city"},
+			arg:   p,
+			want:  []any{int64(10), "FirstName1", "1@domain.com", "Tokyo"},
+		},
+		{
+			name:  "pointer to struct",
+			names: []string{"last_name"},
+			arg:   &p,
+			want:  []any{"LastName1"},
+		},
+		{
+			name:  "nil pointer on path",
+			names: []string{"address.city"},
+			arg:   named{},
+			want:  []any{nil},
+		},
+		{
+			name:  "map[string]any",
+			names: []string{"a", "b"},
+			arg:   map[string]any{"a": 1, "b": "two"},
+			want:  []any{1, "two"},
+		},
+		{
+			name:  "map of other type",
+			names: []string{"a"},
+			arg:   map[string]int{"a": 1},
+			want:  []any{1},
+		},
+		{
+			name:  "no names",
+			names: nil,
+			arg:   nil,
+			want:  []any{},
+		},
+		{
+			name:    "missing struct field",
+			names:   []string{"unknown"},
+			arg:     p,
+			wantErr: "could not find name unknown",
+		},
+		{
+			name:    "missing map key",
+			names:   []string{"unknown"},
+			arg:     map[string]any{},
+			wantErr: "could not find name unknown",
+		},
+		{
+			name:    "nil pointer",
+			names:   []string{"id"},
+			arg:     (*named)(nil),
+			wantErr: "nil",
+		},
+		{
+			name:    "unsupported type",
+			names:   []string{"id"},
+			arg:     10,
+			wantErr: "must be bound from a struct or map",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := bindArgs(DefaultMapper, tt.names, tt.arg)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+					t.Fatalf("got %+v want error containing %q", err, tt.wantErr)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("got %+v want nil", err)
+			}
+			if !cmp.Equal(got, tt.want) {
+				t.Fatalf("(-got +want) %s", cmp.Diff(got, tt.want))
+			}
+		})
+	}
+}
+
+func TestNamed(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, conn := newFakeDB(t, func(query string, args []driver.NamedValue) (fakeResult, error) {
+		res, err := personRows(query, args)
+		res.rowsAffected = 1
+		return res, err
+	})
+	db := NewDB(sqlDB)
+	arg := optionPerson{FirstName: "John", LastName: "Doe"}
+
+	t.Run("NamedSelect", func(t *testing.T) {
+		got, err := NamedSelect[string](ctx, db, "SELECT first_name FROM person WHERE first_name = :first_name", arg).Collect()
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		if len(got) != 2 {
+			t.Fatalf("got %d rows want 2", len(got))
+		}
+		if want := []any{"John"}; !cmp.Equal(conn.lastArgs(), want) {
+			t.Fatalf("(-got +want) %s", cmp.Diff(conn.lastArgs(), want))
+		}
+	})
+	t.Run("NamedGet", func(t *testing.T) {
+		got, err := NamedGet[string](ctx, db, "SELECT first_name FROM person WHERE last = :last", map[string]any{"last": "Doe"})
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		if got != "John" {
+			t.Fatalf("got %q want %q", got, "John")
+		}
+		if want := []any{"Doe"}; !cmp.Equal(conn.lastArgs(), want) {
+			t.Fatalf("(-got +want) %s", cmp.Diff(conn.lastArgs(), want))
+		}
+	})
+	t.Run("NamedExec", func(t *testing.T) {
+		res, err := NamedExec(ctx, db, "UPDATE person SET last_name = :lastname WHERE first_name = :first_name", &arg)
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		if n, _ := res.RowsAffected(); n != 1 {
+			t.Fatalf("got %d rows affected want 1", n)
+		}
+		if want := []any{"Doe", "John"}; !cmp.Equal(conn.lastArgs(), want) {
+			t.Fatalf("(-got +want) %s", cmp.Diff(conn.lastArgs(), want))
+		}
+		if got, want := conn.lastQuery(), "UPDATE person SET last_name = ? WHERE first_name = ?"; got != want {
+			t.Fatalf("got %q want %q", got, want)
+		}
+	})
+	t.Run("bind error", func(t *testing.T) {
+		n := len(conn.queries())
+		tests := map[string]struct {
+			query string
+			run   func(query string) error
+		}{
+			"NamedSelect": {
+				query: "SELECT first_name FROM person WHERE x = :x",
+				run: func(query string) error {
+					_, err := NamedSelect[string](ctx, db, query, arg).Collect()
+					return err
+				},
+			},
+			"NamedGet": {
+				query: "SELECT first_name FROM person WHERE y = :y",
+				run: func(query string) error {
+					_, err := NamedGet[string](ctx, db, query, arg)
+					return err
+				},
+			},
+			"NamedExec": {
+				query: "DELETE FROM person WHERE z = :z",
+				run: func(query string) error {
+					_, err := NamedExec(ctx, db, query, arg)
+					return err
+				},
+			},
+		}
+		for name, tt := range tests {
+			err := tt.run(tt.query)
+			var qe *QueryError
+			if !errors.As(err, &qe) || qe.Query != tt.query || !strings.Contains(err.Error(), "could not find name") {
+				t.Errorf("%s: got %+v want a QueryError of the query with a bind error", name, err)
+			}
+		}
+		if len(conn.queries()) != n {
+			t.Fatal("query was sent despite bind error")
+		}
+	})
+}
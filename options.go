@@ -0,0 +1,87 @@
+package dbx
+
+import (
+	"database/sql"
+)
+
+// Option configures a DB created by NewDB or Open.
+type Option func(*DB)
+
+// NewDB wraps an existing *sql.DB and applies opts.
+// Without options the DB behaves like a plain *sql.DB passed to the
+// helpers in this package, using DefaultMapper and the Dialect detected
+// from the driver of db.
+func NewDB(db *sql.DB, opts ...Option) *DB {
+	d := &DB{
+		DB:      db,
+		mapper:  DefaultMapper,
+		dialect: dialectOf(db.Driver()),
+	}
+	for _, opt := range opts {
+		opt(d)
+	}
+	return d
+}
+
+// Open opens a database with sql.Open and wraps it with NewDB.
+// The Dialect is chosen from driverName unless WithDialect is given.
+func Open(driverName, dataSourceName string, opts ...Option) (*DB, error) {
+	db, err := sql.Open(driverName, dataSourceName)
+	if err != nil {
+		return nil, err
+	}
+	if d, ok := driverDialects[driverName]; ok {
+		opts = append([]Option{WithDialect(d)}, opts...)
+	}
+	return NewDB(db, opts...), nil
+}
+
+// WithMapper sets the Mapper used to map columns to struct fields.
+// A nil Mapper is ignored.
+func WithMapper(m *Mapper) Option {
+	return func(db *DB) {
+		if m != nil {
+			db.mapper = m
+		}
+	}
+}
+
+// WithDialect sets the Dialect used to rewrite placeholders and quote
+// identifiers. A nil Dialect is ignored.
+func WithDialect(d Dialect) Option {
+	return func(db *DB) {
+		if d != nil {
+			db.dialect = d
+		}
+	}
+}
+
+// WithUnsafe allows queries to return columns that have no matching struct
+// field. The values of such columns are silently discarded.
+func WithUnsafe() Option {
+	return func(db *DB) {
+		db.isUnsafe = true
+	}
+}
+
+// WithNoRowsError makes Get return a *QueryError wrapping sql.ErrNoRows when
+// the query has no results, instead of the zero value of T and a nil error.
+func WithNoRowsError() Option {
+	return func(db *DB) {
+		db.noRowsErr = true
+	}
+}
+
+// WithErrorHandler registers fn to be called with every error returned by
+// the functions of this package. Handlers run in the order they were
+// registered, each receiving the result of the previous one, and can be used
+// to translate driver errors into application errors. Errors are classified
+// before they are passed to the first handler, so handlers can check for
+// classes such as ErrDuplicateKey with errors.Is.
+func WithErrorHandler(fn func(error) error) Option {
+	return func(db *DB) {
+		if fn != nil {
+			db.errHandlers = append(db.errHandlers, fn)
+		}
+	}
+}
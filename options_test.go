@@ -0,0 +1,216 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type optionPerson struct {
+	FirstName string `db:"first_name"`
+	LastName  string
+}
+
+// personRows answers "SELECT first_name FROM ..." with a single column
+// and any other query with both columns. "WHERE 1=0" returns no rows.
+func personRows(query string, _ []driver.NamedValue) (fakeResult, error) {
+	res := rowsOf([]string{"first_name", "last"},
+		[]driver.Value{"John", "Doe"},
+		[]driver.Value{"Jane", "Roe"},
+	)
+	if strings.HasPrefix(query, "SELECT first_name FROM") {
+		res.columns = res.columns[:1]
+		for i, row := range res.rows {
+			res.rows[i] = row[:1]
+		}
+	}
+	if strings.Contains(query, "WHERE 1=0") {
+		res.rows = nil
+	}
+	return res, nil
+}
+
+func TestOpen(t *testing.T) {
+	db, err := Open("mysql", "root:@tcp(localhost:1)/dbx", WithUnsafe())
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	defer db.Close()
+	if !db.isUnsafe || db.mapper != DefaultMapper {
+		t.Fatalf("options not applied: %+v", db)
+	}
+
+	if _, err := Open("unknown-driver", ""); err == nil {
+		t.Fatal("got nil want error for unknown driver")
+	}
+}
+
+func TestNewDB(t *testing.T) {
+	sqlDB, _ := newFakeDB(t, personRows)
+	db := NewDB(sqlDB)
+	if db.mapper != DefaultMapper || db.isUnsafe || db.noRowsErr || len(db.errHandlers) != 0 {
+		t.Fatalf("unexpected defaults: %+v", db)
+	}
+	// without options a *DB must behave the same as the *sql.DB it wraps
+	want, err := Select[string](context.Background(), sqlDB, "SELECT first_name FROM person").Collect()
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	got, err := Select[string](context.Background(), db, "SELECT first_name FROM person").Collect()
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+	}
+}
+
+func TestWithMapper(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, _ := newFakeDB(t, personRows)
+
+	// the default mapper maps LastName to lastname, which has no matching column
+	_, err := Select[optionPerson](ctx, NewDB(sqlDB), "SELECT first_name, last FROM person").Collect()
+	if err == nil || !strings.Contains(err.Error(), "missing destination name last") {
+		t.Fatalf("got %+v want missing destination error", err)
+	}
+
+	db := NewDB(sqlDB, WithMapper(NewMapperFunc("db", func(s string) string {
+		if s == "LastName" {
+			return "last"
+		}
+		return strings.ToLower(s)
+	})))
+	got, err := Select[optionPerson](ctx, db, "SELECT first_name, last FROM person").Collect()
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	want := []optionPerson{{"John", "Doe"}, {"Jane", "Roe"}}
+	if !cmp.Equal(got, want) {
+		t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+	}
+
+	if NewDB(sqlDB, WithMapper(nil)).mapper != DefaultMapper {
+		t.Fatal("nil mapper should be ignored")
+	}
+}
+
+func TestWithUnsafe(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, _ := newFakeDB(t, personRows)
+
+	type firstOnly struct {
+		FirstName string `db:"first_name"`
+	}
+	if _, err := Get[firstOnly](ctx, NewDB(sqlDB), "SELECT * FROM person"); err == nil {
+		t.Fatal("got nil want missing destination error")
+	}
+
+	got, err := Get[firstOnly](ctx, NewDB(sqlDB, WithUnsafe()), "SELECT * FROM person")
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if want := (firstOnly{FirstName: "John"}); got != want {
+		t.Fatalf("got %+v want %+v", got, want)
+	}
+}
+
+func TestWithNoRowsError(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, _ := newFakeDB(t, personRows)
+	const query = "SELECT first_name FROM person WHERE 1=0"
+
+	got, err := Get[string](ctx, NewDB(sqlDB), query)
+	if err != nil || got != "" {
+		t.Fatalf("got (%q, %+v) want zero value and nil", got, err)
+	}
+
+	_, err = Get[string](ctx, NewDB(sqlDB, WithNoRowsError()), query)
+	if !errors.Is(err, sql.ErrNoRows) {
+		t.Fatalf("got %+v want %+v", err, sql.ErrNoRows)
+	}
+	var qe *QueryError
+	if !errors.As(err, &qe) || qe.Query != query || qe.Row != -1 {
+		t.Fatalf("got %+v want a QueryError of the query", err)
+	}
+
+	// Select is not affected by the option
+	rows, err := Select[string](ctx, NewDB(sqlDB, WithNoRowsError()), query).Collect()
+	if err != nil || len(rows) != 0 {
+		t.Fatalf("got (%v, %+v) want empty and nil", rows, err)
+	}
+}
+
+func TestWithErrorHandler(t *testing.T) {
+	ctx := context.Background()
+	errQuery := errors.New("query failed")
+	errNotFound := errors.New("not found")
+	sqlDB, _ := newFakeDB(t, func(query string, args []driver.NamedValue) (fakeResult, error) {
+		if strings.HasPrefix(query, "BROKEN") {
+			return fakeResult{}, errQuery
+		}
+		return personRows(query, args)
+	})
+
+	var calls []string
+	db := NewDB(sqlDB,
+		WithNoRowsError(),
+		WithErrorHandler(func(err error) error {
+			calls = append(calls, "first")
+			if errors.Is(err, sql.ErrNoRows) {
+				return errNotFound
+			}
+			return err
+		}),
+		WithErrorHandler(func(err error) error {
+			calls = append(calls, "second")
+			return err
+		}),
+		WithErrorHandler(nil),
+	)
+
+	t.Run("Get no rows", func(t *testing.T) {
+		calls = nil
+		_, err := Get[string](ctx, db, "SELECT first_name FROM person WHERE 1=0")
+		if !errors.Is(err, errNotFound) {
+			t.Fatalf("got %+v want %+v", err, errNotFound)
+		}
+		if want := []string{"first", "second"}; !cmp.Equal(calls, want) {
+			t.Fatalf("(-got +want) %s", cmp.Diff(calls, want))
+		}
+	})
+	t.Run("Select query error", func(t *testing.T) {
+		calls = nil
+		_, err := Select[string](ctx, db, "BROKEN").Collect()
+		if !errors.Is(err, errQuery) {
+			t.Fatalf("got %+v want %+v", err, errQuery)
+		}
+		if len(calls) != 2 {
+			t.Fatalf("got %d handler calls want 2", len(calls))
+		}
+	})
+	t.Run("scan error", func(t *testing.T) {
+		calls = nil
+		_, err := Get[string](ctx, db, "SELECT first_name, last FROM person")
+		if err == nil || !strings.Contains(err.Error(), "non-struct dest type") {
+			t.Fatalf("got %+v want non-struct error", err)
+		}
+		if len(calls) != 2 {
+			t.Fatalf("got %d handler calls want 2", len(calls))
+		}
+	})
+	t.Run("no error", func(t *testing.T) {
+		calls = nil
+		if _, err := Get[string](ctx, db, "SELECT first_name FROM person"); err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		if len(calls) != 0 {
+			t.Fatalf("got %d handler calls want 0", len(calls))
+		}
+	})
+}
@@ -0,0 +1,204 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"io/fs"
+	"regexp"
+	"slices"
+	"strings"
+)
+
+// Query is a named query of a QuerySet.
+type Query struct {
+	Name string
+	SQL  string
+	File string // the file the query was loaded from
+}
+
+// QuerySet is a set of named queries loaded from .sql files with
+// LoadQueries.
+type QuerySet struct {
+	queries map[string]Query
+}
+
+// queryNameComment starts a query in a .sql file, e.g.
+// -- name: ListPeople :many
+// The optional :command is ignored, so files can be shared with dbxgen. The
+// :name parameters of such queries are bound by NamedSelectQuery,
+// NamedGetQuery and NamedExecQuery.
+var queryNameComment = regexp.MustCompile(`^--\s*name:\s*(\S+)(\s+:\w+)?\s*$`)
+
+// LoadQueries loads the queries of the files of fsys that match pattern,
+// usually from an embed.FS:
+//
+//	//go:embed queries/*.sql
+//	var sqlFiles embed.FS
+//
+//	var queries = dbx.MustLoadQueries(sqlFiles, "queries/*.sql")
+//
+// Each query starts with a comment that gives its name, and ends at the
+// next one or at the end of the file:
+//
+//	-- name: ListPeople
+//	SELECT * FROM person WHERE last_name = ?;
+//
+// Comments directly after the name are not part of the query, and neither
+// is a trailing semicolon. An error is returned if no file matches, if a
+// name is defined twice, or if a file has SQL before its first name.
+func LoadQueries(fsys fs.FS, pattern string) (*QuerySet, error) {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) == 0 {
+		return nil, fmt.Errorf("no files match %s", pattern)
+	}
+	qs := &QuerySet{queries: make(map[string]Query)}
+	for _, file := range files {
+		src, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		if err := qs.parse(file, string(src)); err != nil {
+			return nil, err
+		}
+	}
+	return qs, nil
+}
+
+// MustLoadQueries is like LoadQueries but panics on error. It simplifies
+// the initialization of global variables.
+func MustLoadQueries(fsys fs.FS, pattern string) *QuerySet {
+	qs, err := LoadQueries(fsys, pattern)
+	if err != nil {
+		panic(err)
+	}
+	return qs
+}
+
+// parse adds the queries of file, whose contents are src.
+func (qs *QuerySet) parse(file, src string) error {
+	var q *Query
+	var body []string
+	var line int // of the name comment of q
+	add := func() error {
+		if q == nil {
+			return nil
+		}
+		q.SQL = strings.TrimSuffix(strings.TrimSpace(strings.Join(body, "\n")), ";")
+		if q.SQL == "" {
+			return fmt.Errorf("%s:%d: query %s is empty", file, line, q.Name)
+		}
+		if prev, ok := qs.queries[q.Name]; ok {
+			return fmt.Errorf("%s:%d: query %s is already defined in %s", file, line, q.Name, prev.File)
+		}
+		qs.queries[q.Name] = *q
+		return nil
+	}
+	for i, text := range strings.Split(src, "\n") {
+		trimmed := strings.TrimSpace(text)
+		if m := queryNameComment.FindStringSubmatch(trimmed); m != nil {
+			if err := add(); err != nil {
+				return err
+			}
+			q, body, line = &Query{Name: m[1], File: file}, nil, i+1
+			continue
+		}
+		isComment := trimmed == "" || strings.HasPrefix(trimmed, "--")
+		switch {
+		case q == nil && !isComment:
+			return fmt.Errorf("%s:%d: query without a name comment", file, i+1)
+		case q == nil, len(body) == 0 && isComment:
+		default:
+			body = append(body, text)
+		}
+	}
+	return add()
+}
+
+// Lookup returns the query with the given name, or an error if there is
+// none.
+func (qs *QuerySet) Lookup(name string) (Query, error) {
+	q, ok := qs.queries[name]
+	if !ok {
+		return Query{}, fmt.Errorf("unknown query %s", name)
+	}
+	return q, nil
+}
+
+// MustLookup is like Lookup but panics if there is no query with the given
+// name. Use it to look up queries when initializing global variables, so
+// that unknown names are found when the program starts:
+//
+//	var listPeople = queries.MustLookup("ListPeople")
+func (qs *QuerySet) MustLookup(name string) Query {
+	q, err := qs.Lookup(name)
+	if err != nil {
+		panic(err)
+	}
+	return q
+}
+
+// Names returns the names of the queries, sorted.
+func (qs *QuerySet) Names() []string {
+	names := make([]string, 0, len(qs.queries))
+	for name := range qs.queries {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// SelectQuery is like Select, but runs a named query. The name of the
+// query is reported to hooks, as with WithQueryName.
+func SelectQuery[T any](ctx context.Context, q Queryer, query Query, args ...any) Scanner[T] {
+	return Select[T](WithQueryName(ctx, query.Name), q, query.SQL, args...)
+}
+
+// GetQuery is like Get, but runs a named query. The name of the query is
+// reported to hooks, as with WithQueryName.
+func GetQuery[T any](ctx context.Context, q Queryer, query Query, args ...any) (T, error) {
+	return Get[T](WithQueryName(ctx, query.Name), q, query.SQL, args...)
+}
+
+// ExecQuery is like Exec, but runs a named query. The name of the query is
+// reported to hooks, as with WithQueryName.
+func ExecQuery(ctx context.Context, e Execer, query Query, args ...any) (sql.Result, error) {
+	return Exec(WithQueryName(ctx, query.Name), e, query.SQL, args...)
+}
+
+// NamedSelectQuery is like NamedSelect, but runs a named query. The name of
+// the query is reported to hooks, as with WithQueryName.
+func NamedSelectQuery[T any](ctx context.Context, q Queryer, query Query, arg any) Scanner[T] {
+	return NamedSelect[T](WithQueryName(ctx, query.Name), q, query.SQL, arg)
+}
+
+// NamedGetQuery is like NamedGet, but runs a named query. The name of the
+// query is reported to hooks, as with WithQueryName.
+func NamedGetQuery[T any](ctx context.Context, q Queryer, query Query, arg any) (T, error) {
+	return NamedGet[T](WithQueryName(ctx, query.Name), q, query.SQL, arg)
+}
+
+// NamedExecQuery is like NamedExec, but runs a named query. The name of the
+// query is reported to hooks, as with WithQueryName.
+func NamedExecQuery(ctx context.Context, e Execer, query Query, arg any) (sql.Result, error) {
+	return NamedExec(WithQueryName(ctx, query.Name), e, query.SQL, arg)
+}
+
+type queryNameKey struct{}
+
+// WithQueryName returns a context that names the statements run with it.
+// The name is reported to hooks in QueryInfo.Name, and used as a label by
+// Metrics. Unlike the query itself it has a low cardinality, so it is
+// suitable as a label of metrics and as the name of spans.
+func WithQueryName(ctx context.Context, name string) context.Context {
+	return context.WithValue(ctx, queryNameKey{}, name)
+}
+
+// QueryName returns the name set with WithQueryName, or "".
+func QueryName(ctx context.Context) string {
+	name, _ := ctx.Value(queryNameKey{}).(string)
+	return name
+}
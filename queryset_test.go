@@ -0,0 +1,227 @@
+package dbx
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"testing/fstest"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+var queryFiles = fstest.MapFS{
+	"queries/person.sql": {Data: []byte(`-- queries of the person table
+
+-- name: ListPeople :many
+-- ListPeople returns everyone.
+SELECT first_name, last
+FROM person;
+
+-- name: FirstName
+SELECT first_name FROM person -- the first one
+LIMIT 1
+`)},
+	"queries/place.sql": {Data: []byte("-- name: UpdatePlace :exec\r\nUPDATE place SET city = ?\r\n")},
+	"schema.sql":        {Data: []byte("CREATE TABLE person (first_name text);")},
+}
+
+func TestLoadQueries(t *testing.T) {
+	qs, err := LoadQueries(queryFiles, "queries/*.sql")
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if diff := cmp.Diff(qs.Names(), []string{"FirstName", "ListPeople", "UpdatePlace"}); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+	var got []Query
+	for _, name := range qs.Names() {
+		q, err := qs.Lookup(name)
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		got = append(got, q)
+	}
+	want := []Query{
+		{Name: "FirstName", SQL: "SELECT first_name FROM person -- the first one\nLIMIT 1", File: "queries/person.sql"},
+		{Name: "ListPeople", SQL: "SELECT first_name, last\nFROM person", File: "queries/person.sql"},
+		{Name: "UpdatePlace", SQL: "UPDATE place SET city = ?", File: "queries/place.sql"},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+
+	if _, err := qs.Lookup("DeletePlace"); err == nil || err.Error() != "unknown query DeletePlace" {
+		t.Fatalf("got %v want unknown query error", err)
+	}
+	func() {
+		defer func() {
+			if r := recover(); r == nil {
+				t.Fatal("got no panic want unknown query panic")
+			}
+		}()
+		qs.MustLookup("DeletePlace")
+	}()
+}
+
+func TestLoadQueriesErrors(t *testing.T) {
+	tests := []struct {
+		name  string
+		files fstest.MapFS
+		want  string
+	}{
+		{
+			name: "duplicate",
+			files: fstest.MapFS{
+				"a.sql": {Data: []byte("-- name: Q\nSELECT 1")},
+				"b.sql": {Data: []byte("\n-- name: Q\nSELECT 2")},
+			},
+			want: "b.sql:2: query Q is already defined in a.sql",
+		},
+		{
+			name:  "no name",
+			files: fstest.MapFS{"a.sql": {Data: []byte("-- comment\nSELECT 1;\n-- name: Q\nSELECT 2")}},
+			want:  "a.sql:2: query without a name comment",
+		},
+		{
+			name:  "empty",
+			files: fstest.MapFS{"a.sql": {Data: []byte("-- name: Q\n-- nothing\n;\n-- name: R\nSELECT 1")}},
+			want:  "a.sql:1: query Q is empty",
+		},
+		{
+			name:  "no files",
+			files: fstest.MapFS{"a.txt": {}},
+			want:  "no files match *.sql",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := LoadQueries(tt.files, "*.sql"); err == nil || err.Error() != tt.want {
+				t.Fatalf("got %v want %s", err, tt.want)
+			}
+		})
+	}
+}
+
+func TestNamedQueryHooks(t *testing.T) {
+	ctx := context.Background()
+	qs := MustLoadQueries(queryFiles, "queries/*.sql")
+	var names []string
+	metrics := NewMetrics()
+	db, _ := txDB(t, map[string]error{"UPDATE place SET city = ?": errors.New("fail")},
+		WithHooks(nameHook{&names}), WithMetrics(metrics))
+
+	if _, err := SelectQuery[string](ctx, db, qs.MustLookup("FirstName")).Collect(); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if _, err := GetQuery[string](ctx, db, qs.MustLookup("FirstName")); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if _, err := ExecQuery(ctx, db, qs.MustLookup("UpdatePlace"), "Tokyo"); err == nil {
+		t.Fatal("got nil want error")
+	}
+	// the same query without a name is counted separately
+	if _, err := Get[string](ctx, db, "SELECT first_name FROM person LIMIT 1"); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if diff := cmp.Diff(names, []string{"FirstName", "FirstName", "UpdatePlace", ""}); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+
+	var got []string
+	for _, qm := range metrics.Snapshot() {
+		got = append(got, qm.Name+" "+qm.Query)
+	}
+	want := []string{
+		" SELECT first_name FROM person LIMIT ?",
+		"FirstName SELECT first_name FROM person LIMIT ?",
+		"UpdatePlace UPDATE place SET city = ?",
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+	var buf bytes.Buffer
+	if err := metrics.Export(ctx, &PrometheusExporter{W: &buf}); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if want := `dbx_query_errors_total{name="UpdatePlace",fingerprint="`; !strings.Contains(buf.String(), want) {
+		t.Fatalf("got %s want %s", buf.String(), want)
+	}
+}
+
+func TestNamedParamQueries(t *testing.T) {
+	ctx := context.Background()
+	// a file shared with dbxgen, whose queries have :name parameters
+	qs := MustLoadQueries(fstest.MapFS{"people.sql": {Data: []byte(`-- name: ListPeople :many
+SELECT first_name FROM person WHERE last IN (:last_names);
+
+-- name: RenamePerson :exec
+UPDATE person SET first_name = :first_name WHERE last = :last;
+`)}}, "*.sql")
+	var names []string
+	db, conn := txDB(t, nil, WithHooks(nameHook{&names}))
+	listPeople, renamePerson := qs.MustLookup("ListPeople"), qs.MustLookup("RenamePerson")
+	lastNames := map[string]any{"last_names": []string{"Doe", "Roe"}}
+
+	tests := []struct {
+		name      string
+		run       func() error
+		wantQuery string
+		wantArgs  []any
+	}{
+		{
+			name: "NamedSelectQuery",
+			run: func() error {
+				_, err := NamedSelectQuery[string](ctx, db, listPeople, lastNames).Collect()
+				return err
+			},
+			wantQuery: "SELECT first_name FROM person WHERE last IN (?, ?)",
+			wantArgs:  []any{"Doe", "Roe"},
+		},
+		{
+			name: "NamedGetQuery",
+			run: func() error {
+				_, err := NamedGetQuery[string](ctx, db, listPeople, lastNames)
+				return err
+			},
+			wantQuery: "SELECT first_name FROM person WHERE last IN (?, ?)",
+			wantArgs:  []any{"Doe", "Roe"},
+		},
+		{
+			name: "NamedExecQuery",
+			run: func() error {
+				_, err := NamedExecQuery(ctx, db, renamePerson, map[string]any{"first_name": "Jim", "last": "Doe"})
+				return err
+			},
+			wantQuery: "UPDATE person SET first_name = ? WHERE last = ?",
+			wantArgs:  []any{"Jim", "Doe"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.run(); err != nil {
+				t.Fatalf("got %+v want nil", err)
+			}
+			if got := conn.lastQuery(); got != tt.wantQuery {
+				t.Fatalf("got %q want %q", got, tt.wantQuery)
+			}
+			if got := conn.lastArgs(); !cmp.Equal(got, tt.wantArgs) {
+				t.Fatalf("(-got +want) %s", cmp.Diff(got, tt.wantArgs))
+			}
+		})
+	}
+	if diff := cmp.Diff(names, []string{"ListPeople", "ListPeople", "RenamePerson"}); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+}
+
+// nameHook records the names of queries.
+type nameHook struct{ names *[]string }
+
+func (h nameHook) BeforeQuery(ctx context.Context, info QueryInfo) context.Context {
+	*h.names = append(*h.names, info.Name)
+	return ctx
+}
+
+func (h nameHook) AfterQuery(context.Context, QueryInfo, int, error) {}
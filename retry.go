@@ -0,0 +1,95 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"math/rand/v2"
+	"time"
+)
+
+// RetryPolicy configures how WithTx re-runs a transaction that failed with a
+// retryable error, such as a deadlock. The zero value disables retries.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of times the transaction is run,
+	// including the first attempt. Values below 2 disable retries.
+	MaxAttempts int
+	// Backoff is the delay before the first retry. It is doubled for each
+	// following retry, up to MaxBackoff.
+	Backoff time.Duration
+	// MaxBackoff caps the delay between retries. 0 means no cap.
+	MaxBackoff time.Duration
+	// Jitter randomizes each delay by up to this fraction of it, e.g. 0.2
+	// for ±20%, so that conflicting transactions don't retry in lockstep.
+	Jitter float64
+	// Retryable reports whether err should be retried. If nil, deadlocks and
+	// lock wait timeouts are retried.
+	Retryable func(err error) bool
+}
+
+// DefaultRetryPolicy retries a transaction up to twice after a deadlock or
+// lock wait timeout.
+var DefaultRetryPolicy = RetryPolicy{
+	MaxAttempts: 3,
+	Backoff:     10 * time.Millisecond,
+	MaxBackoff:  time.Second,
+	Jitter:      0.2,
+}
+
+// WithRetryPolicy sets the RetryPolicy used by DB.WithTx.
+func WithRetryPolicy(p RetryPolicy) Option {
+	return func(db *DB) {
+		db.retry = p
+	}
+}
+
+// WithTxRetry is like WithTx, but uses policy instead of the RetryPolicy of
+// db. fn is re-run in a new transaction each time it is retried, so it must
+// not have side effects outside the transaction.
+//
+// No retry is made once ctx is done, and waiting for the next attempt is
+// interrupted if ctx is cancelled. In that case the error of the last
+// attempt is returned joined with the error of ctx.
+func (db *DB) WithTxRetry(ctx context.Context, opts *sql.TxOptions, policy RetryPolicy, fn func(tx *Tx) error) error {
+	retryable := policy.Retryable
+	if retryable == nil {
+		retryable = isRetryable
+	}
+	for attempt := 1; ; attempt++ {
+		err := db.withTx(ctx, opts, fn)
+		if err == nil || attempt >= policy.MaxAttempts || !retryable(err) {
+			return err
+		}
+		if ctx.Err() != nil {
+			return errors.Join(err, ctx.Err())
+		}
+		timer := time.NewTimer(policy.backoff(attempt))
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return errors.Join(err, ctx.Err())
+		case <-timer.C:
+		}
+	}
+}
+
+// backoff returns the delay after the given attempt, starting at 1.
+func (p RetryPolicy) backoff(attempt int) time.Duration {
+	d := p.Backoff
+	for i := 1; i < attempt && (p.MaxBackoff <= 0 || d < p.MaxBackoff); i++ {
+		d *= 2
+	}
+	if p.MaxBackoff > 0 {
+		d = min(d, p.MaxBackoff)
+	}
+	if p.Jitter > 0 {
+		d += time.Duration(float64(d) * p.Jitter * (2*rand.Float64() - 1))
+	}
+	return max(d, 0)
+}
+
+// isRetryable reports whether err is a deadlock or lock wait timeout, after
+// which the whole transaction can be retried.
+func isRetryable(err error) bool {
+	return IsDeadlock(err) || IsLockTimeout(err)
+}
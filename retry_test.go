@@ -0,0 +1,152 @@
+package dbx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/go-sql-driver/mysql"
+	"github.com/google/go-cmp/cmp"
+)
+
+var (
+	errDeadlock    = &mysql.MySQLError{Number: 1213, Message: "Deadlock found when trying to get lock"}
+	errLockTimeout = &mysql.MySQLError{Number: 1205, Message: "Lock wait timeout exceeded"}
+)
+
+// failingDB returns a DB that fails the first n UPDATE statements with err.
+func failingDB(t *testing.T, n int, err error, opts ...Option) (*DB, *fakeConnector) {
+	t.Helper()
+	sqlDB, conn := newFakeDB(t, func(query string, _ []driver.NamedValue) (fakeResult, error) {
+		if query == "UPDATE" && n > 0 {
+			n--
+			return fakeResult{}, err
+		}
+		return fakeResult{rowsAffected: 1}, nil
+	})
+	return NewDB(sqlDB, opts...), conn
+}
+
+func TestWithTxRetry(t *testing.T) {
+	ctx := context.Background()
+	policy := RetryPolicy{MaxAttempts: 3, Backoff: time.Millisecond}
+	update := func(attempts *int) func(tx *Tx) error {
+		return func(tx *Tx) error {
+			*attempts++
+			_, err := Exec(ctx, tx, "UPDATE")
+			return err
+		}
+	}
+
+	tests := []struct {
+		name         string
+		failures     int
+		err          error
+		policy       RetryPolicy
+		wantAttempts int
+		wantErr      error
+	}{
+		{name: "deadlock", failures: 2, err: errDeadlock, policy: policy, wantAttempts: 3},
+		{name: "lock wait timeout", failures: 1, err: errLockTimeout, policy: policy, wantAttempts: 2},
+		{name: "attempts exhausted", failures: 3, err: errDeadlock, policy: policy, wantAttempts: 3, wantErr: errDeadlock},
+		{name: "not retryable", failures: 1, err: &mysql.MySQLError{Number: 1062}, policy: policy, wantAttempts: 1, wantErr: &mysql.MySQLError{Number: 1062}},
+		{name: "zero policy", failures: 1, err: errDeadlock, policy: RetryPolicy{}, wantAttempts: 1, wantErr: errDeadlock},
+		{
+			name:     "custom retryable",
+			failures: 1,
+			err:      errLockTimeout,
+			policy: RetryPolicy{MaxAttempts: 2, Retryable: func(err error) bool {
+				return errors.Is(err, errLockTimeout)
+			}},
+			wantAttempts: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			db, conn := failingDB(t, tt.failures, tt.err)
+			attempts := 0
+			err := db.WithTxRetry(ctx, nil, tt.policy, update(&attempts))
+			if tt.wantErr == nil && err != nil {
+				t.Fatalf("got %+v want nil", err)
+			}
+			if tt.wantErr != nil && !errors.Is(err, tt.wantErr) {
+				t.Fatalf("got %+v want %+v", err, tt.wantErr)
+			}
+			if attempts != tt.wantAttempts {
+				t.Fatalf("got %d attempts want %d", attempts, tt.wantAttempts)
+			}
+			// every attempt runs in a new transaction
+			var begins int
+			for _, q := range conn.queries() {
+				if q == "BEGIN" {
+					begins++
+				}
+			}
+			if begins != tt.wantAttempts {
+				t.Fatalf("got %d transactions want %d", begins, tt.wantAttempts)
+			}
+		})
+	}
+
+	t.Run("WithRetryPolicy", func(t *testing.T) {
+		db, conn := failingDB(t, 1, errDeadlock, WithRetryPolicy(policy))
+		attempts := 0
+		if err := db.WithTx(ctx, nil, update(&attempts)); err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		want := []string{"BEGIN", "UPDATE", "ROLLBACK", "BEGIN", "UPDATE", "COMMIT"}
+		if got := conn.queries(); !cmp.Equal(got, want) {
+			t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+		}
+	})
+	t.Run("cancelled context", func(t *testing.T) {
+		db, _ := failingDB(t, 5, errDeadlock)
+		ctx, cancel := context.WithCancel(ctx)
+		attempts := 0
+		err := db.WithTxRetry(ctx, nil, RetryPolicy{MaxAttempts: 5, Backoff: time.Hour}, func(tx *Tx) error {
+			attempts++
+			_, err := Exec(ctx, tx, "UPDATE")
+			cancel()
+			return err
+		})
+		if !errors.Is(err, errDeadlock) || !errors.Is(err, context.Canceled) {
+			t.Fatalf("got %+v want %+v and %+v", err, errDeadlock, context.Canceled)
+		}
+		if attempts != 1 {
+			t.Fatalf("got %d attempts want 1", attempts)
+		}
+	})
+	t.Run("cancelled during backoff", func(t *testing.T) {
+		db, _ := failingDB(t, 5, errDeadlock)
+		ctx, cancel := context.WithTimeout(ctx, 10*time.Millisecond)
+		defer cancel()
+		attempts := 0
+		start := time.Now()
+		err := db.WithTxRetry(ctx, nil, RetryPolicy{MaxAttempts: 5, Backoff: time.Hour}, update(&attempts))
+		if !errors.Is(err, errDeadlock) || !errors.Is(err, context.DeadlineExceeded) {
+			t.Fatalf("got %+v want %+v and %+v", err, errDeadlock, context.DeadlineExceeded)
+		}
+		if attempts != 1 || time.Since(start) > time.Minute {
+			t.Fatalf("got %d attempts in %s want 1 attempt", attempts, time.Since(start))
+		}
+	})
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	p := RetryPolicy{Backoff: 10 * time.Millisecond, MaxBackoff: 50 * time.Millisecond}
+	want := []time.Duration{10, 20, 40, 50, 50}
+	for i, w := range want {
+		if got := p.backoff(i + 1); got != w*time.Millisecond {
+			t.Errorf("attempt %d: got %s want %s", i+1, got, w*time.Millisecond)
+		}
+	}
+
+	p.Jitter = 0.5
+	for range 100 {
+		if got := p.backoff(1); got < 5*time.Millisecond || got > 15*time.Millisecond {
+			t.Fatalf("got %s want within 50%% of 10ms", got)
+		}
+	}
+}
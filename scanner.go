@@ -3,165 +3,226 @@ package dbx
 import (
 	"context"
 	"database/sql"
-	"errors"
 	"fmt"
 	"iter"
 	"reflect"
 )
 
-func scan[T any](ctx context.Context, q Queryer, query string, args ...any) iter.Seq2[T, error] {
+// scan returns the rows of query as type T, binding query and args as in
+// bind.
+func scan[T any](ctx context.Context, q Queryer, query string, args []any, expand bool) iter.Seq2[T, error] {
 	return func(yield func(T, error) bool) {
-		rows, err := q.QueryContext(ctx, query, args...)
+		db := dbOf(q)
+		bound, boundArgs, err := db.bind(query, args, expand)
 		if err != nil {
 			var t T
-			yield(t, err)
+			yield(t, db.queryErr(err, query, args, nil, -1))
 			return
 		}
-		defer func() { _ = rows.Close() }()
+		scanBound[T](ctx, q, bound, boundArgs)(yield)
+	}
+}
+
+// scanBound returns the rows of the query bound as type T, where the query
+// and its args have already been prepared by bind.
+func scanBound[T any](ctx context.Context, q Queryer, bound string, boundArgs []any) iter.Seq2[T, error] {
+	return func(yield func(T, error) bool) {
+		db := dbOf(q)
+		m, isUnsafe := DefaultMapper, false
+		if db != nil {
+			m, isUnsafe = db.mapper, db.isUnsafe
+		}
+		// fail yields err as a *QueryError after passing it through the
+		// DB's error handlers, with the columns and the index of the current
+		// row once they are known
+		var columns []string
+		row := -1
+		var failed error
+		fail := func(err error) {
+			var t T
+			failed = db.queryErr(err, bound, boundArgs, columns, row)
+			yield(t, failed)
+		}
 
+		// sql.RawBytes is reused by the next call to rows.Next, which happens
+		// before the caller is done with a yielded row
 		base := reflect.TypeFor[T]()
-		scannable := isScannable(derefType(base))
-		columns, err := rows.Columns()
+		if err := rawBytesErr(m, base); err != nil {
+			fail(err)
+			return
+		}
+
+		// the hooks are called once iteration ends, whether all rows were
+		// scanned, an error occurred or the caller stopped early
+		_, inTx := q.(*Tx)
+		ctx, after := db.beforeQuery(ctx, inTx, QuerySelect, bound, boundArgs)
+		scanned := 0
+		defer func() { after(scanned, failed) }()
+
+		rows, err := db.queryContext(ctx, q, bound, boundArgs)
 		if err != nil {
-			var t T
-			yield(t, err)
+			fail(err)
+			return
+		}
+		defer func() { _ = rows.Close() }()
+
+		scannable := isScannable(m, derefType(base))
+		if columns, err = rows.Columns(); err != nil {
+			fail(err)
 			return
 		}
 
 		// if it's a base type make sure it only has 1 column; if not return an error
 		if scannable && len(columns) > 1 {
-			var t T
-			yield(t, fmt.Errorf("non-struct dest type %s with >1 columns (%d)", base.Kind(), len(columns)))
+			fail(fmt.Errorf("non-struct dest type %s with >1 columns (%d)", base.Kind(), len(columns)))
 			return
 		}
 
 		if scannable { // non-struct or sql.Scanner type
 			for rows.Next() {
+				row++
 				var t T
 				if err := rows.Scan(&t); err != nil {
-					yield(t, fmt.Errorf("rows.Scan failure for type %T: %w", t, err))
+					fail(fmt.Errorf("rows.Scan failure for type %T: %w", t, err))
 					return
 				}
+				scanned++
 				if !yield(t, nil) {
 					return
 				}
 			}
-		} else { // struct type
-			isUnsafe := false
-			var m *Mapper
-			switch q := q.(type) {
-			case *DB:
-				m = q.mapper
-				isUnsafe = q.isUnsafe
-			default:
-				m = DefaultMapper
+		} else if !isUnsafe && m.dbxgenRules && reflect.PointerTo(derefType(base)).Implements(_columnScannerInterface) {
+			// the destinations are returned by the type itself, usually with
+			// code generated by dbxgen, so no reflection is needed per row
+			isPtr := base.Kind() == reflect.Ptr
+			newT := func() (*T, ColumnScanner) {
+				t := new(T)
+				if isPtr {
+					*t = reflect.New(base.Elem()).Interface().(T)
+					return t, any(*t).(ColumnScanner)
+				}
+				return t, any(t).(ColumnScanner)
 			}
-
-			fields := m.TraversalsByName(base, columns)
+			// check the columns before the first row, as for other structs
+			_, probe := newT()
+			if _, err := probe.ScanColumns(columns); err != nil {
+				fail(err)
+				return
+			}
+			for rows.Next() {
+				row++
+				t, cs := newT()
+				values, err := cs.ScanColumns(columns)
+				if err != nil {
+					fail(err)
+					return
+				}
+				if err := rows.Scan(values...); err != nil {
+					fail(fmt.Errorf("failed to scan values for type %T: %w", *t, err))
+					return
+				}
+				scanned++
+				if !yield(*t, nil) {
+					return
+				}
+			}
+		} else { // struct type
+			plan := scanPlanFor(m, derefType(base), columns)
 			// if we are not unsafe and are missing fields, return an error
-			if f, err := missingFields(fields); err != nil && !isUnsafe {
-				var t T
-				yield(t, fmt.Errorf("missing destination name %s in %T", columns[f], base))
+			if plan.missing >= 0 && !isUnsafe {
+				fail(fmt.Errorf("missing destination name %s in %T", columns[plan.missing], base))
 				return
 			}
 			values := make([]any, len(columns))
+			discard := new(any) // for columns without a field
 
-			var vp reflect.Value
-			var t *T
-
+			isPtr := base.Kind() == reflect.Ptr
 			for rows.Next() {
-				if base.Kind() == reflect.Ptr {
-					vp = reflect.New(base.Elem())
+				row++
+				var t T
+				var v reflect.Value
+				if isPtr {
+					vp := reflect.New(base.Elem())
+					t = vp.Interface().(T)
+					v = vp.Elem()
 				} else {
-					t = new(T)
-					vp = reflect.ValueOf(t)
-				}
-				v := reflect.Indirect(vp)
-
-				// fill values slice with default values of the correct types
-				if err := fieldsByTraversal(v, fields, values); err != nil {
-					var t T
-					yield(t, err)
-					return
+					v = reflect.ValueOf(&t).Elem()
 				}
 
 				// scan into the struct field pointers and yield the result
+				plan.values(v, values, discard)
 				if err := rows.Scan(values...); err != nil {
-					var t T
-					yield(t, fmt.Errorf("failed to scan values for type %T: %w", t, err))
+					fail(fmt.Errorf("failed to scan values for type %T: %w", t, err))
 					return
 				}
-
-				if base.Kind() == reflect.Ptr {
-					t, ok := vp.Interface().(T)
-					if !ok {
-						yield(t, fmt.Errorf("failed to convert pointer of type %T", t))
-						return
-					}
-					if !yield(t, nil) {
-						return
-					}
-				} else {
-					if !yield(*t, nil) {
-						return
-					}
+				scanned++
+				if !yield(t, nil) {
+					return
 				}
 			}
 		}
 
 		if err := rows.Err(); err != nil {
-			var t T
-			yield(t, err)
+			row++ // the row after the last scanned one failed
+			fail(err)
 			return
 		}
 	}
 }
 
-func missingFields(traversals [][]int) (field int, err error) {
-	for i, t := range traversals {
-		if len(t) == 0 {
-			return i, errors.New("missing field")
+// ColumnScanner is implemented by struct types that return the
+// destinations to scan columns into themselves, so that Get and Select
+// don't need reflection to find them. The method is usually generated by
+// dbxgen, and is only used if the DB is not created with WithUnsafe, and
+// its Mapper maps names like DefaultMapper, with the db tag and
+// strings.ToLower. Other Mappers map the fields with reflection.
+type ColumnScanner interface {
+	// ScanColumns returns pointers to the fields to scan columns into, or
+	// an error if a column has no field.
+	ScanColumns(columns []string) ([]any, error)
+}
+
+var (
+	_scannerInterface       = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
+	_columnScannerInterface = reflect.TypeOf((*ColumnScanner)(nil)).Elem()
+)
+
+var _rawBytesType = reflect.TypeFor[sql.RawBytes]()
+
+// rawBytesErr returns an error if t is sql.RawBytes, a pointer to it, or a
+// struct with a field of one of those types, which Get and Select can't
+// scan into. Use ScanRaw to read columns without copying them.
+func rawBytesErr(m *Mapper, t reflect.Type) error {
+	base := derefType(t)
+	if base == _rawBytesType {
+		return fmt.Errorf("sql.RawBytes dest type %s is invalid after the next row; use []byte or ScanRaw", t)
+	}
+	if isScannable(m, base) {
+		return nil
+	}
+	for _, fi := range m.TypeMap(base).Index {
+		if derefType(fi.Field.Type) == _rawBytesType {
+			return fmt.Errorf("sql.RawBytes field %s in %s is invalid after the next row; use []byte or ScanRaw", fi.Path, t)
 		}
 	}
-	return 0, nil
+	return nil
 }
 
-var _scannerInterface = reflect.TypeOf((*sql.Scanner)(nil)).Elem()
-
-// isScannable takes the reflect.Type and the actual dest value and returns
+// isScannable takes the Mapper and the reflect.Type of the dest value and returns
 // whether or not it's Scannable. Something is scannable if:
 //   - it is not a struct
 //   - it implements sql.Scanner
 //   - it has no exported fields
-func isScannable(t reflect.Type) bool {
+func isScannable(m *Mapper, t reflect.Type) bool {
 	if reflect.PointerTo(t).Implements(_scannerInterface) {
 		return true
 	}
 	if t.Kind() != reflect.Struct {
 		return true
 	}
-	// it's not important that we use the right mapper for this particular object,
-	// we're only concerned on how many exported fields this struct has
-	// TODO 2024/02/24 @Jimeux decide how mapper is handled
-	return len(DefaultMapper.TypeMap(t).Index) == 0 // len(mapper().TypeMap(t).Traversal) == 0
-}
-
-func fieldsByTraversal(v reflect.Value, traversals [][]int, values []any) error {
-	v = reflect.Indirect(v)
-	if v.Kind() != reflect.Struct {
-		return errors.New("fieldsByTraversal argument must be a struct")
-	}
-
-	for i, traversal := range traversals {
-		if len(traversal) == 0 {
-			values[i] = new(any)
-			continue
-		}
-		// create a value of the field type and set it as any in values
-		f := fieldByIndexes(v, traversal)
-		values[i] = f.Addr().Interface()
-	}
-	return nil
+	// use the same mapper that will be used to scan the struct, so that
+	// fields ignored by its tag rules are not counted
+	return len(m.TypeMap(t).Index) == 0
 }
 
 // fieldByIndexes returns a value for the field given by the struct traversal
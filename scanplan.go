@@ -0,0 +1,126 @@
+package dbx
+
+import (
+	"reflect"
+	"strings"
+	"unsafe"
+)
+
+// scanPlan is how the columns of a query are scanned into a struct type.
+// Plans are computed once per Mapper, type and list of columns, so that
+// scanning a row only has to collect the addresses of the fields.
+type scanPlan struct {
+	fields  []fieldPlan // by column
+	missing int         // index of the first column without a field, or -1
+	// direct is true if all fields are reached without following pointers,
+	// in which case their addresses are computed from their offsets.
+	direct bool
+}
+
+// fieldPlan is how a column is scanned into a field of a struct.
+type fieldPlan struct {
+	traversal []int // nil if the column has no field
+	// offset is the offset of the field from the start of the struct if it
+	// is reached without following pointers, which is when direct is true.
+	offset uintptr
+	direct bool
+	typ    reflect.Type // the type of the field if direct is true
+	// addr returns the address of the field in the struct v, allocating
+	// nil pointers on the way if needed.
+	addr func(v reflect.Value) any
+}
+
+// planKey identifies a scanPlan in the cache of a Mapper.
+type planKey struct {
+	t       reflect.Type
+	columns string // joined by NUL, which can't appear in column names
+}
+
+// scanPlanFor returns the plan for scanning columns into t, which must be a
+// struct type.
+func scanPlanFor(m *Mapper, t reflect.Type, columns []string) *scanPlan {
+	key := planKey{t: t, columns: strings.Join(columns, "\x00")}
+	if p, ok := m.plans.Load(key); ok {
+		return p.(*scanPlan)
+	}
+	p, _ := m.plans.LoadOrStore(key, newScanPlan(m, t, columns))
+	return p.(*scanPlan)
+}
+
+func newScanPlan(m *Mapper, t reflect.Type, columns []string) *scanPlan {
+	p := &scanPlan{fields: make([]fieldPlan, len(columns)), missing: -1, direct: true}
+	for i, traversal := range m.TraversalsByName(t, columns) {
+		if len(traversal) == 0 {
+			if p.missing < 0 {
+				p.missing = i
+			}
+			continue
+		}
+		p.fields[i] = newFieldPlan(t, traversal)
+		p.direct = p.direct && p.fields[i].direct
+	}
+	return p
+}
+
+func newFieldPlan(t reflect.Type, traversal []int) fieldPlan {
+	fp := fieldPlan{traversal: traversal, direct: true}
+	for _, i := range traversal {
+		if t.Kind() != reflect.Struct {
+			// a pointer to a struct that has to be followed
+			fp.direct = false
+			break
+		}
+		f := t.Field(i)
+		fp.offset += f.Offset
+		t = f.Type
+	}
+	if fp.direct {
+		fp.typ = t
+		fp.addr = func(v reflect.Value) any {
+			return v.FieldByIndex(traversal).Addr().Interface()
+		}
+	} else {
+		fp.offset = 0
+		fp.addr = func(v reflect.Value) any {
+			return fieldByIndexes(v, traversal).Addr().Interface()
+		}
+	}
+	return fp
+}
+
+// values sets values to the addresses of the fields of the struct v to scan
+// the columns into. Columns without a field are scanned into discard. v
+// must be addressable.
+func (p *scanPlan) values(v reflect.Value, values []any, discard *any) {
+	if p.direct {
+		p.offsetValues(v, values, discard)
+	} else {
+		p.reflectValues(v, values, discard)
+	}
+}
+
+// offsetValues is values for direct plans. The address of each field is
+// computed from the address of v and the offset of the field, instead of
+// walking the fields with reflection.
+func (p *scanPlan) offsetValues(v reflect.Value, values []any, discard *any) {
+	base := v.Addr().UnsafePointer()
+	for i := range p.fields {
+		if f := &p.fields[i]; f.addr != nil {
+			values[i] = reflect.NewAt(f.typ, unsafe.Add(base, f.offset)).Interface()
+		} else {
+			values[i] = discard
+		}
+	}
+}
+
+// reflectValues is values for any plan. Nil pointers on the way to fields
+// are allocated as in fieldByIndexes.
+func (p *scanPlan) reflectValues(v reflect.Value, values []any, discard *any) {
+	for i := range p.fields {
+		if f := &p.fields[i]; f.addr != nil {
+			values[i] = f.addr(v)
+		} else {
+			values[i] = discard
+		}
+	}
+}
@@ -0,0 +1,400 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+	"github.com/jmoiron/sqlx"
+)
+
+type planInner struct {
+	B string `db:"b"`
+}
+
+type planOuter struct {
+	A string `db:"a"`
+	planInner
+	P *planInner `db:"p"`
+}
+
+func TestScanPlan(t *testing.T) {
+	typ := reflect.TypeFor[planOuter]()
+	m := NewMapperFunc("db", nil)
+	p := scanPlanFor(m, typ, []string{"a", "b", "x", "p.b"})
+	if p != scanPlanFor(m, typ, []string{"a", "b", "x", "p.b"}) {
+		t.Fatal("got a new plan want the cached plan")
+	}
+	if p == scanPlanFor(m, typ, []string{"a", "b"}) {
+		t.Fatal("got the cached plan want a plan for other columns")
+	}
+	if p == scanPlanFor(NewMapperFunc("db", nil), typ, []string{"a", "b", "x", "p.b"}) {
+		t.Fatal("got the cached plan want a plan for another mapper")
+	}
+	if p.missing != 2 {
+		t.Fatalf("got missing %d want 2", p.missing)
+	}
+	// p.b is reached through a pointer
+	if p.direct || !scanPlanFor(m, typ, []string{"a", "b"}).direct {
+		t.Fatal("got direct plan with pointer field or indirect plan without")
+	}
+
+	type field struct {
+		Traversal []int
+		Offset    uintptr
+		Direct    bool
+	}
+	var got []field
+	for _, f := range p.fields {
+		got = append(got, field{f.traversal, f.offset, f.direct})
+	}
+	want := []field{
+		{Traversal: []int{0}, Offset: 0, Direct: true},
+		{Traversal: []int{1, 0}, Offset: typ.Field(1).Offset, Direct: true},
+		{},
+		{Traversal: []int{2, 0}},
+	}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+
+	var v planOuter
+	values := make([]any, 4)
+	discard := new(any)
+	p.values(reflect.ValueOf(&v).Elem(), values, discard)
+	if values[0] != &v.A || values[1] != &v.B || values[2] != discard || v.P == nil || values[3] != &v.P.B {
+		t.Fatalf("got %v want addresses of the fields of %p", values, &v)
+	}
+}
+
+type fuzzInner struct {
+	I int64  `db:"i"`
+	S string `db:"s"`
+}
+
+// fuzzFlat has fields of many kinds, all reached without pointers.
+type fuzzFlat struct {
+	A   int64         `db:"a"`
+	B   string        `db:"b"`
+	F   float64       `db:"f"`
+	OK  bool          `db:"ok"`
+	P   *string       `db:"p"`
+	N   sql.NullInt64 `db:"n"`
+	Raw []byte        `db:"raw"`
+	U8  uint8         `db:"u8"`
+	fuzzInner
+	Nested fuzzInner `db:"nested"`
+}
+
+// FuzzScanPlanPaths checks that the offset and reflection paths of a scan
+// plan fill structs identically.
+func FuzzScanPlanPaths(f *testing.F) {
+	f.Add(int64(1), "a", 1.5, true, []byte("raw"), int64(-1))
+	f.Add(int64(0), "", 0.0, false, []byte(nil), int64(255))
+	f.Fuzz(func(t *testing.T, a int64, b string, fl float64, ok bool, raw []byte, i int64) {
+		columns := []string{"a", "b", "f", "ok", "p", "n", "raw", "u8", "i", "s", "nested.i", "nested.s", "unknown"}
+		row := []driver.Value{a, b, fl, ok, nil, nil, raw, i, i, b, a, b, b}
+		if ok {
+			row[4], row[5] = b, a
+		}
+		sqlDB, _ := newFakeDB(t, func(string, []driver.NamedValue) (fakeResult, error) {
+			return rowsOf(columns, row), nil
+		})
+
+		plan := scanPlanFor(DefaultMapper, reflect.TypeFor[fuzzFlat](), columns)
+		if !plan.direct {
+			t.Fatal("got indirect plan want direct")
+		}
+		scanWith := func(values func(reflect.Value, []any, *any)) (fuzzFlat, error) {
+			var v fuzzFlat
+			rows, err := sqlDB.Query("SELECT")
+			if err != nil {
+				return v, err
+			}
+			defer func() { _ = rows.Close() }()
+			rows.Next()
+			dest := make([]any, len(columns))
+			values(reflect.ValueOf(&v).Elem(), dest, new(any))
+			return v, rows.Scan(dest...)
+		}
+		offset, offsetErr := scanWith(plan.offsetValues)
+		reflected, reflectErr := scanWith(plan.reflectValues)
+		if (offsetErr == nil) != (reflectErr == nil) {
+			t.Fatalf("got errors %v and %v want both or neither", offsetErr, reflectErr)
+		}
+		if diff := cmp.Diff(offset, reflected, cmp.AllowUnexported(fuzzFlat{})); diff != "" {
+			t.Fatalf("(-offset +reflect) %s", diff)
+		}
+		if offsetErr == nil && (offset.A != a || offset.Nested.S != b) {
+			t.Fatalf("got %+v want fields scanned", offset)
+		}
+	})
+}
+
+// wideStruct is a struct with many columns for benchmarks.
+type wideStruct struct {
+	C0  int64   `db:"c0"`
+	C1  string  `db:"c1"`
+	C2  float64 `db:"c2"`
+	C3  bool    `db:"c3"`
+	C4  int64   `db:"c4"`
+	C5  string  `db:"c5"`
+	C6  float64 `db:"c6"`
+	C7  bool    `db:"c7"`
+	C8  int64   `db:"c8"`
+	C9  string  `db:"c9"`
+	C10 float64 `db:"c10"`
+	C11 bool    `db:"c11"`
+	C12 int64   `db:"c12"`
+	C13 string  `db:"c13"`
+	C14 float64 `db:"c14"`
+	C15 bool    `db:"c15"`
+	C16 int64   `db:"c16"`
+	C17 string  `db:"c17"`
+	C18 float64 `db:"c18"`
+	C19 bool    `db:"c19"`
+	C20 int64   `db:"c20"`
+	C21 string  `db:"c21"`
+	C22 float64 `db:"c22"`
+	C23 bool    `db:"c23"`
+	C24 int64   `db:"c24"`
+	C25 string  `db:"c25"`
+	C26 float64 `db:"c26"`
+	C27 bool    `db:"c27"`
+	C28 int64   `db:"c28"`
+	C29 string  `db:"c29"`
+	C30 float64 `db:"c30"`
+	C31 bool    `db:"c31"`
+	C32 int64   `db:"c32"`
+	C33 string  `db:"c33"`
+	C34 float64 `db:"c34"`
+	C35 bool    `db:"c35"`
+	C36 int64   `db:"c36"`
+	C37 string  `db:"c37"`
+	C38 float64 `db:"c38"`
+	C39 bool    `db:"c39"`
+}
+
+// wideDB returns a fake database that answers every query with n rows of
+// the columns of wideStruct.
+func wideDB(b *testing.B, n int) *DB {
+	b.Helper()
+	columns := make([]string, 40)
+	for i := range columns {
+		columns[i] = "c" + strconv.Itoa(i)
+	}
+	rows := make([][]driver.Value, n)
+	for i := range rows {
+		rows[i] = make([]driver.Value, len(columns))
+		for j := range rows[i] {
+			switch j % 4 {
+			case 0:
+				rows[i][j] = int64(i)
+			case 1:
+				rows[i][j] = "value"
+			case 2:
+				rows[i][j] = float64(i) / 2
+			case 3:
+				rows[i][j] = i%2 == 0
+			}
+		}
+	}
+	sqlDB, _ := newFakeDB(b, func(string, []driver.NamedValue) (fakeResult, error) {
+		return rowsOf(columns, rows...), nil
+	})
+	return NewDB(sqlDB)
+}
+
+// legacyScan scans rows the way scan did before scan plans: the traversals
+// are looked up for every query and the fields are found for every row.
+func legacyScan[T any](ctx context.Context, db *DB, query string) ([]T, error) {
+	rows, err := db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer func() { _ = rows.Close() }()
+	columns, err := rows.Columns()
+	if err != nil {
+		return nil, err
+	}
+	fields := db.mapper.TraversalsByName(reflect.TypeFor[T](), columns)
+	for _, f := range fields {
+		if len(f) == 0 {
+			return nil, errors.New("missing field")
+		}
+	}
+	values := make([]any, len(columns))
+	var ts []T
+	for rows.Next() {
+		t := new(T)
+		v := reflect.ValueOf(t).Elem()
+		for i, traversal := range fields {
+			values[i] = fieldByIndexes(v, traversal).Addr().Interface()
+		}
+		if err := rows.Scan(values...); err != nil {
+			return nil, err
+		}
+		ts = append(ts, *t)
+	}
+	return ts, rows.Err()
+}
+
+func BenchmarkScanWide(b *testing.B) {
+	ctx := context.Background()
+	for _, n := range []int{1, 100} {
+		db := wideDB(b, n)
+		b.Run("legacy/rows="+strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				if _, err := legacyScan[wideStruct](ctx, db, "SELECT"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run("plan/rows="+strconv.Itoa(n), func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				if _, err := Select[wideStruct](ctx, db, "SELECT").Collect(); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+		b.Run("sqlx/rows="+strconv.Itoa(n), func(b *testing.B) {
+			sqlxDB := sqlx.NewDb(db.DB, "mysql")
+			b.ReportAllocs()
+			for range b.N {
+				var ts []wideStruct
+				if err := sqlxDB.SelectContext(ctx, &ts, "SELECT"); err != nil {
+					b.Fatal(err)
+				}
+			}
+		})
+	}
+}
+
+func BenchmarkScanPlanValues(b *testing.B) {
+	columns := make([]string, 40)
+	for i := range columns {
+		columns[i] = "c" + strconv.Itoa(i)
+	}
+	plan := scanPlanFor(DefaultMapper, reflect.TypeFor[wideStruct](), columns)
+	values := make([]any, len(columns))
+	discard := new(any)
+	for name, fn := range map[string]func(reflect.Value, []any, *any){
+		"offset":  plan.offsetValues,
+		"reflect": plan.reflectValues,
+	} {
+		b.Run(name, func(b *testing.B) {
+			b.ReportAllocs()
+			for range b.N {
+				var v wideStruct
+				fn(reflect.ValueOf(&v).Elem(), values, discard)
+			}
+		})
+	}
+}
+
+// generatedPerson implements ColumnScanner as dbxgen would, and records
+// that it was used.
+type generatedPerson struct {
+	First     string `db:"first_name"`
+	Last      string `db:"last"`
+	Generated bool   `db:"-"`
+}
+
+func (v *generatedPerson) ScanColumns(columns []string) ([]any, error) {
+	v.Generated = true
+	dest := make([]any, len(columns))
+	for i, column := range columns {
+		switch column {
+		case "first_name":
+			dest[i] = &v.First
+		case "last":
+			dest[i] = &v.Last
+		default:
+			return nil, fmt.Errorf("missing destination name %s in %T", column, v)
+		}
+	}
+	return dest, nil
+}
+
+// jsonPerson has a ScanColumns method generated for its db tags.
+type jsonPerson struct {
+	Name  string `db:"name" json:"first_name"`
+	Email string `db:"mail" json:"email"`
+}
+
+func (v *jsonPerson) ScanColumns(columns []string) ([]any, error) {
+	dest := make([]any, len(columns))
+	for i, column := range columns {
+		switch column {
+		case "name":
+			dest[i] = &v.Name
+		case "mail":
+			dest[i] = &v.Email
+		default:
+			return nil, fmt.Errorf("missing destination name %s in %T", column, v)
+		}
+	}
+	return dest, nil
+}
+
+func TestColumnScanner(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, _ := newFakeDB(t, func(query string, args []driver.NamedValue) (fakeResult, error) {
+		if strings.Contains(query, "email") {
+			return rowsOf([]string{"first_name", "email"}, []driver.Value{"John", "john@example.com"}), nil
+		}
+		return personRows(query, args)
+	})
+
+	db := NewDB(sqlDB)
+	got, err := Select[generatedPerson](ctx, db, "SELECT * FROM person").Collect()
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	want := []generatedPerson{{"John", "Doe", true}, {"Jane", "Roe", true}}
+	if diff := cmp.Diff(got, want); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+	ptr, err := Get[*generatedPerson](ctx, db, "SELECT * FROM person")
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if diff := cmp.Diff(ptr, &want[0]); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+	if _, err := Get[generatedPerson](ctx, db, "SELECT first_name, email FROM person"); err == nil || !strings.Contains(err.Error(), "missing destination name email") {
+		t.Fatalf("got %v want missing destination error", err)
+	}
+
+	// the generated mapping is only used with the rules of DefaultMapper
+	jsonDB := NewDB(sqlDB, WithMapper(NewMapperFunc("json", strings.ToLower)))
+	jp, err := Get[jsonPerson](ctx, jsonDB, "SELECT first_name, email FROM person")
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if diff := cmp.Diff(jp, jsonPerson{Name: "John", Email: "john@example.com"}); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+	if !NewMapperFunc("db", strings.ToLower).dbxgenRules || NewMapperFunc("db", nil).dbxgenRules {
+		t.Fatal("got wrong dbxgenRules")
+	}
+
+	// unsafe DBs ignore missing fields, which generated code can't do
+	unsafeDB := NewDB(sqlDB, WithUnsafe())
+	p, err := Get[generatedPerson](ctx, unsafeDB, "SELECT first_name, email FROM person")
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if diff := cmp.Diff(p, generatedPerson{First: "John"}); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+}
@@ -0,0 +1,249 @@
+package dbx
+
+import (
+	"container/list"
+	"context"
+	"database/sql"
+	"errors"
+	"sync"
+)
+
+// WithStmtCache makes the DB prepare the queries run by the functions of
+// this package and keep up to size of the prepared statements in an LRU
+// cache keyed by query. Statements are closed when they are evicted and
+// when the DB is closed. Transactions use the statements that are already
+// cached through sql.Tx.StmtContext, but run other queries unprepared, since
+// preparing them for the cache would take a second connection from the
+// pool. A size of 0 or less disables the cache.
+//
+// The cache is only useful for queries that are run repeatedly with
+// different args. Queries with literals, or with slice args that are
+// expanded to a varying number of placeholders, fill it with statements
+// that are never reused.
+func WithStmtCache(size int) Option {
+	return func(db *DB) {
+		if size > 0 {
+			db.stmts = newStmtCache(size)
+		} else {
+			db.stmts = nil
+		}
+	}
+}
+
+// StmtCacheStats are the counters of the statement cache of a DB.
+type StmtCacheStats struct {
+	Hits      int64 // queries run with a cached statement
+	Misses    int64 // queries that had to be prepared, outside transactions
+	Evictions int64 // statements closed to make room for others
+	Len       int   // statements currently cached
+}
+
+// StmtCacheStats returns the counters of the statement cache of db. It
+// returns zero values if the cache is disabled.
+func (db *DB) StmtCacheStats() StmtCacheStats {
+	if db.stmts == nil {
+		return StmtCacheStats{}
+	}
+	return db.stmts.stats()
+}
+
+// Close closes the cached statements, if any, and then the database.
+func (db *DB) Close() error {
+	var err error
+	if db.stmts != nil {
+		err = db.stmts.close()
+	}
+	return errors.Join(err, db.DB.Close())
+}
+
+// stmtCache is an LRU cache of prepared statements.
+type stmtCache struct {
+	size int
+
+	mu      sync.Mutex
+	lru     *list.List // of *cachedStmt, most recently used first
+	byQuery map[string]*list.Element
+	counts  StmtCacheStats
+	closed  bool
+}
+
+// cachedStmt is a statement in a stmtCache. It is closed when it has been
+// evicted and is no longer in use.
+type cachedStmt struct {
+	*sql.Stmt
+	query   string
+	refs    int // number of queries using the statement
+	evicted bool
+}
+
+func newStmtCache(size int) *stmtCache {
+	return &stmtCache{
+		size:    size,
+		lru:     list.New(),
+		byQuery: make(map[string]*list.Element),
+	}
+}
+
+// get returns the statement for query, preparing it with db if it is not
+// cached. The statement must be released with put once it has been used.
+func (c *stmtCache) get(ctx context.Context, db *sql.DB, query string) (*cachedStmt, error) {
+	c.mu.Lock()
+	if s, ok := c.lookup(query); ok {
+		c.counts.Hits++
+		c.mu.Unlock()
+		return s, nil
+	}
+	c.counts.Misses++
+	c.mu.Unlock()
+
+	// prepare without holding the lock, so that other queries are not blocked
+	stmt, err := db.PrepareContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.closed {
+		// the cache was closed while preparing; the caller closes the
+		// statement on put
+		return &cachedStmt{Stmt: stmt, query: query, refs: 1, evicted: true}, nil
+	}
+	if s, ok := c.lookup(query); ok {
+		// prepared concurrently by another query
+		_ = stmt.Close()
+		return s, nil
+	}
+	s := &cachedStmt{Stmt: stmt, query: query, refs: 1}
+	c.byQuery[query] = c.lru.PushFront(s)
+	for c.lru.Len() > c.size {
+		c.counts.Evictions++
+		// errors closing evicted statements don't affect the query
+		_ = c.evict(c.lru.Back())
+	}
+	return s, nil
+}
+
+// cached returns the statement for query if it is cached, without
+// preparing it. The statement must be released with put once it has been
+// used.
+func (c *stmtCache) cached(query string) (*cachedStmt, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.lookup(query)
+	if ok {
+		c.counts.Hits++
+	}
+	return s, ok
+}
+
+// lookup returns the cached statement for query and marks it as used.
+// c.mu must be held.
+func (c *stmtCache) lookup(query string) (*cachedStmt, bool) {
+	e, ok := c.byQuery[query]
+	if !ok {
+		return nil, false
+	}
+	c.lru.MoveToFront(e)
+	s := e.Value.(*cachedStmt)
+	s.refs++
+	return s, true
+}
+
+// evict removes e from the cache and closes its statement unless it is in
+// use, in which case it is closed by put. c.mu must be held.
+func (c *stmtCache) evict(e *list.Element) error {
+	s := c.lru.Remove(e).(*cachedStmt)
+	delete(c.byQuery, s.query)
+	s.evicted = true
+	if s.refs == 0 {
+		return s.Close()
+	}
+	return nil
+}
+
+// put releases a statement returned by get.
+func (c *stmtCache) put(s *cachedStmt) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s.refs--
+	if s.evicted && s.refs == 0 {
+		_ = s.Close()
+	}
+}
+
+func (c *stmtCache) stats() StmtCacheStats {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	st := c.counts
+	st.Len = c.lru.Len()
+	return st
+}
+
+// close evicts all statements. Statements prepared after close are closed
+// as soon as they have been used.
+func (c *stmtCache) close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.closed = true
+	var err error
+	for c.lru.Len() > 0 {
+		err = errors.Join(err, c.evict(c.lru.Front()))
+	}
+	return err
+}
+
+// queryContext runs query with q, using a cached statement if db has a
+// statement cache and q is db or one of its transactions.
+func (db *DB) queryContext(ctx context.Context, q Queryer, query string, args []any) (*sql.Rows, error) {
+	s, tx, err := db.stmt(ctx, q, query)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return q.QueryContext(ctx, query, args...)
+	}
+	defer db.stmts.put(s)
+	if tx != nil {
+		return tx.StmtContext(ctx, s.Stmt).QueryContext(ctx, args...)
+	}
+	return s.QueryContext(ctx, args...)
+}
+
+// execContext is like queryContext for statements that don't return rows.
+func (db *DB) execContext(ctx context.Context, e Execer, query string, args []any) (sql.Result, error) {
+	s, tx, err := db.stmt(ctx, e, query)
+	if err != nil {
+		return nil, err
+	}
+	if s == nil {
+		return e.ExecContext(ctx, query, args...)
+	}
+	defer db.stmts.put(s)
+	if tx != nil {
+		return tx.StmtContext(ctx, s.Stmt).ExecContext(ctx, args...)
+	}
+	return s.ExecContext(ctx, args...)
+}
+
+// stmt returns the cached statement for query if the statement cache of db
+// can be used for q, and the transaction to run it in if q is a *Tx. It
+// returns a nil statement if the cache can't be used. In a transaction,
+// only statements that are already cached are used, since preparing one
+// with db.DB would wait for a second connection, which may never be free
+// while the transaction holds one.
+func (db *DB) stmt(ctx context.Context, q any, query string) (*cachedStmt, *sql.Tx, error) {
+	if db == nil || db.stmts == nil {
+		return nil, nil, nil
+	}
+	switch q := q.(type) {
+	case *DB:
+		s, err := db.stmts.get(ctx, db.DB, query)
+		return s, nil, err
+	case *Tx:
+		if s, ok := db.stmts.cached(query); ok {
+			return s, q.Tx, nil
+		}
+	}
+	return nil, nil, nil
+}
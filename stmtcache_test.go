@@ -0,0 +1,172 @@
+package dbx
+
+import (
+	"context"
+	"database/sql/driver"
+	"fmt"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+func TestStmtCache(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, conn := newFakeDB(t, func(query string, _ []driver.NamedValue) (fakeResult, error) {
+		return rowsOf([]string{"n"}, []driver.Value{int64(1)}), nil
+	})
+	db := NewDB(sqlDB, WithStmtCache(2))
+
+	for _, q := range []string{"SELECT 1", "SELECT 2", "SELECT 1", "SELECT 3", "SELECT 2"} {
+		if _, err := Get[int](ctx, db, q); err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+	}
+	if _, err := Exec(ctx, db, "SELECT 2"); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	want := StmtCacheStats{Hits: 2, Misses: 4, Evictions: 2, Len: 2}
+	if diff := cmp.Diff(db.StmtCacheStats(), want); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	wantEvents := []string{
+		"PREPARE SELECT 1",
+		"PREPARE SELECT 2",
+		"PREPARE SELECT 3",
+		"CLOSE SELECT 2", // evicted by SELECT 3
+		"PREPARE SELECT 2",
+		"CLOSE SELECT 1", // evicted by SELECT 2
+		"CLOSE SELECT 2", // closed by db.Close
+		"CLOSE SELECT 3",
+	}
+	if diff := cmp.Diff(conn.stmtEvents(), wantEvents); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+	if got := db.StmtCacheStats().Len; got != 0 {
+		t.Fatalf("got %d want 0 cached statements after Close", got)
+	}
+}
+
+func TestStmtCacheTx(t *testing.T) {
+	ctx := context.Background()
+	db, conn := txDB(t, nil, WithStmtCache(10))
+	if _, err := Get[string](ctx, db, "SELECT first_name FROM person"); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+
+	// the cached statement is used, and the UPDATE is not prepared
+	err := db.WithTx(ctx, nil, func(tx *Tx) error {
+		for range 2 {
+			if _, err := Get[string](ctx, tx, "SELECT first_name FROM person"); err != nil {
+				return err
+			}
+		}
+		_, err := Exec(ctx, tx, "UPDATE person SET first_name = ?", "Jim")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	wantQueries := []string{"SELECT first_name FROM person", "BEGIN", "SELECT first_name FROM person", "SELECT first_name FROM person", "UPDATE person SET first_name = ?", "COMMIT"}
+	if diff := cmp.Diff(conn.queries(), wantQueries); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+	want := StmtCacheStats{Hits: 2, Misses: 1, Len: 1}
+	if diff := cmp.Diff(db.StmtCacheStats(), want); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+}
+
+// TestStmtCacheTxOneConn runs uncached queries in a transaction that holds
+// the only connection of the pool, which must not wait for another one to
+// prepare them.
+func TestStmtCacheTxOneConn(t *testing.T) {
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+	db, conn := txDB(t, nil, WithStmtCache(10))
+	db.SetMaxOpenConns(1)
+
+	err := db.WithTx(ctx, nil, func(tx *Tx) error {
+		if _, err := Get[string](ctx, tx, "SELECT first_name FROM person"); err != nil {
+			return err
+		}
+		_, err := Exec(ctx, tx, "UPDATE person SET first_name = ?", "Jim")
+		return err
+	})
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if got := conn.stmtEvents(); len(got) != 0 {
+		t.Fatalf("got %v want no prepared statements", got)
+	}
+	if diff := cmp.Diff(db.StmtCacheStats(), StmtCacheStats{}); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+}
+
+func TestStmtCacheDisabled(t *testing.T) {
+	ctx := context.Background()
+	db, conn := txDB(t, nil, WithStmtCache(10), WithStmtCache(0))
+	if _, err := Get[string](ctx, db, "SELECT first_name FROM person"); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if got := conn.stmtEvents(); len(got) != 0 {
+		t.Fatalf("got %v want no prepared statements", got)
+	}
+	if diff := cmp.Diff(db.StmtCacheStats(), StmtCacheStats{}); diff != "" {
+		t.Fatalf("(-got +want) %s", diff)
+	}
+}
+
+// TestStmtCacheConcurrent evicts statements while they are in use by other
+// goroutines, which must not fail their queries.
+func TestStmtCacheConcurrent(t *testing.T) {
+	ctx := context.Background()
+	db, conn := txDB(t, nil, WithStmtCache(1))
+
+	var wg sync.WaitGroup
+	errs := make(chan error, 8)
+	for g := range 8 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := range 50 {
+				query := fmt.Sprintf("SELECT first_name FROM person WHERE %d = %d", (g+i)%3, (g+i)%3)
+				if _, err := Get[string](ctx, db, query); err != nil {
+					errs <- err
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		t.Fatalf("got %+v want nil", err)
+	}
+
+	if err := db.Close(); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	// every prepared statement has been closed exactly once
+	open := map[string]int{}
+	for _, e := range conn.stmtEvents() {
+		action, query, _ := strings.Cut(e, " ")
+		if action == "PREPARE" {
+			open[query]++
+		} else {
+			open[query]--
+		}
+	}
+	for query, n := range open {
+		if n != 0 {
+			t.Errorf("got %d open statements for %q want 0", n, query)
+		}
+	}
+}
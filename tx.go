@@ -0,0 +1,171 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"strconv"
+)
+
+// Tx is an in-progress transaction started by a DB. It carries the settings
+// of the DB, so queries run through a Tx behave the same as queries run
+// through the DB.
+type Tx struct {
+	*sql.Tx
+	db    *DB
+	depth int // number of savepoints the Tx is nested in; 0 for the outermost Tx
+}
+
+// Begin starts a transaction with the default options.
+func (db *DB) Begin() (*Tx, error) {
+	return db.BeginTx(context.Background(), nil)
+}
+
+// BeginTx starts a transaction. See sql.DB.BeginTx for how ctx and opts are used.
+func (db *DB) BeginTx(ctx context.Context, opts *sql.TxOptions) (*Tx, error) {
+	ctx, after := db.beforeQuery(ctx, true, QueryBegin, "BEGIN", nil)
+	tx, err := db.DB.BeginTx(ctx, opts)
+	after(0, err)
+	if err != nil {
+		return nil, db.handleErr(err)
+	}
+	return &Tx{Tx: tx, db: db}, nil
+}
+
+// WithTx runs fn in a transaction. The transaction is committed if fn
+// returns nil, and rolled back if fn returns an error or panics. A panic is
+// re-raised after the rollback. If the rollback fails, its error is joined
+// with the error returned by fn.
+//
+// If db has a RetryPolicy, the transaction is retried as described in
+// WithTxRetry.
+func (db *DB) WithTx(ctx context.Context, opts *sql.TxOptions, fn func(tx *Tx) error) error {
+	return db.WithTxRetry(ctx, opts, db.retry, fn)
+}
+
+// withTx runs fn in a single transaction.
+func (db *DB) withTx(ctx context.Context, opts *sql.TxOptions, fn func(tx *Tx) error) error {
+	tx, err := db.BeginTx(ctx, opts)
+	if err != nil {
+		return err
+	}
+	return tx.run(ctx, fn)
+}
+
+// WithTx runs fn in a nested transaction scope, using a savepoint named
+// sp_N, where N is the nesting depth. If fn returns nil the savepoint is
+// released, and if fn returns an error or panics, only the changes made
+// since the savepoint are rolled back. The outer transaction is unaffected
+// in both cases, and is committed or rolled back by its own WithTx.
+//
+// fn must not call Commit or Rollback on the nested Tx.
+func (tx *Tx) WithTx(ctx context.Context, fn func(tx *Tx) error) error {
+	d := tx.db.dialect
+	if !d.Capabilities().Savepoints {
+		return tx.db.handleErr(fmt.Errorf("savepoints are not supported by dialect %s", d.Name()))
+	}
+	nested := &Tx{Tx: tx.Tx, db: tx.db, depth: tx.depth + 1}
+	if err := nested.exec(ctx, QueryExec, nested.savepointQuery("create")); err != nil {
+		return tx.db.handleErr(err)
+	}
+	return nested.run(ctx, fn)
+}
+
+// run calls fn and commits or rolls back tx depending on the result.
+func (tx *Tx) run(ctx context.Context, fn func(tx *Tx) error) (err error) {
+	defer func() {
+		if p := recover(); p != nil {
+			_ = tx.rollback(ctx)
+			panic(p)
+		}
+	}()
+
+	if err := fn(tx); err != nil {
+		// database/sql has already rolled back the transaction if ctx is
+		// done, so ErrTxDone is not a failure
+		if rbErr := tx.rollback(ctx); rbErr != nil && !errors.Is(rbErr, sql.ErrTxDone) {
+			return errors.Join(err, fmt.Errorf("rollback failed: %w", tx.db.handleErr(rbErr)))
+		}
+		return err
+	}
+	return tx.db.handleErr(tx.commit(ctx))
+}
+
+// Commit commits the transaction. Unlike sql.Tx.Commit, it is reported to
+// the hooks of the DB.
+func (tx *Tx) Commit() error {
+	return tx.commit(context.Background())
+}
+
+// Rollback aborts the transaction. Unlike sql.Tx.Rollback, it is reported
+// to the hooks of the DB.
+func (tx *Tx) Rollback() error {
+	return tx.rollback(context.Background())
+}
+
+// commit commits the transaction, or releases the savepoint of a nested Tx.
+func (tx *Tx) commit(ctx context.Context) error {
+	if tx.depth == 0 {
+		_, after := tx.db.beforeQuery(ctx, true, QueryCommit, "COMMIT", nil)
+		err := tx.Tx.Commit()
+		after(0, err)
+		return err
+	}
+	query := tx.savepointQuery("release")
+	if query == "" {
+		return nil
+	}
+	return tx.exec(ctx, QueryCommit, query)
+}
+
+// rollback rolls back the transaction, or rolls back to the savepoint of a
+// nested Tx.
+func (tx *Tx) rollback(ctx context.Context) error {
+	if tx.depth == 0 {
+		_, after := tx.db.beforeQuery(ctx, true, QueryRollback, "ROLLBACK", nil)
+		err := tx.Tx.Rollback()
+		after(0, err)
+		return err
+	}
+	return tx.exec(ctx, QueryRollback, tx.savepointQuery("rollback"))
+}
+
+// exec executes a savepoint statement of the given kind and reports it to
+// the hooks of the DB.
+func (tx *Tx) exec(ctx context.Context, kind QueryKind, query string) error {
+	ctx, after := tx.db.beforeQuery(ctx, true, kind, query, nil)
+	_, err := tx.ExecContext(ctx, query)
+	after(0, err)
+	return err
+}
+
+// savepointQuery returns the statement to create, release or rollback to
+// the savepoint of a nested Tx. It returns "" if the dialect has no
+// statement for the action.
+func (tx *Tx) savepointQuery(action string) string {
+	name := "sp_" + strconv.Itoa(tx.depth)
+	switch tx.db.dialect {
+	case SQLServer:
+		switch action {
+		case "create":
+			return "SAVE TRANSACTION " + name
+		case "rollback":
+			return "ROLLBACK TRANSACTION " + name
+		}
+		return ""
+	case Oracle:
+		// Oracle has no RELEASE SAVEPOINT
+		if action == "release" {
+			return ""
+		}
+	}
+	switch action {
+	case "create":
+		return "SAVEPOINT " + name
+	case "release":
+		return "RELEASE SAVEPOINT " + name
+	default:
+		return "ROLLBACK TO SAVEPOINT " + name
+	}
+}
@@ -0,0 +1,337 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// txDB returns a DB whose fake connection fails statements listed in fail
+// with the associated error.
+func txDB(t *testing.T, fail map[string]error, opts ...Option) (*DB, *fakeConnector) {
+	t.Helper()
+	sqlDB, conn := newFakeDB(t, func(query string, args []driver.NamedValue) (fakeResult, error) {
+		if err, ok := fail[query]; ok {
+			return fakeResult{}, err
+		}
+		res, err := personRows(query, args)
+		res.rowsAffected = 1
+		return res, err
+	})
+	return NewDB(sqlDB, opts...), conn
+}
+
+func TestTxSettings(t *testing.T) {
+	ctx := context.Background()
+	errHandled := errors.New("handled")
+	db, _ := txDB(t, nil,
+		WithMapper(NewMapperFunc("db", func(s string) string {
+			if s == "LastName" {
+				return "last"
+			}
+			return strings.ToLower(s)
+		})),
+		WithDialect(Postgres),
+		WithNoRowsError(),
+		WithErrorHandler(func(err error) error {
+			if errors.Is(err, sql.ErrNoRows) {
+				return errHandled
+			}
+			return err
+		}),
+	)
+
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	defer tx.Rollback()
+
+	// the mapper of the DB is used, so the last column maps to LastName
+	got, err := Get[optionPerson](ctx, tx, "SELECT first_name, last FROM person WHERE a = ?", 1)
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	if want := (optionPerson{"John", "Doe"}); got != want {
+		t.Fatalf("got %+v want %+v", got, want)
+	}
+	// no rows error and error handlers are used
+	if _, err := Get[string](ctx, tx, "SELECT first_name FROM person WHERE 1=0"); !errors.Is(err, errHandled) {
+		t.Fatalf("got %+v want %+v", err, errHandled)
+	}
+	if dbOf(tx) != db {
+		t.Fatal("dbOf(tx) is not the parent DB")
+	}
+
+	// a *sql.Tx still works, but with the default settings
+	sqlTx, err := db.DB.BeginTx(ctx, nil)
+	if err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	defer sqlTx.Rollback()
+	if _, err := Get[optionPerson](ctx, sqlTx, "SELECT first_name, last FROM person"); err == nil {
+		t.Fatal("got nil want missing destination error with DefaultMapper")
+	}
+}
+
+func TestWithTx(t *testing.T) {
+	ctx := context.Background()
+	errFn := errors.New("fn failed")
+
+	t.Run("commit", func(t *testing.T) {
+		db, conn := txDB(t, nil)
+		err := db.WithTx(ctx, nil, func(tx *Tx) error {
+			_, err := Exec(ctx, tx, "UPDATE person SET a = ?", 1)
+			return err
+		})
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		want := []string{"BEGIN", "UPDATE person SET a = ?", "COMMIT"}
+		if got := conn.queries(); !cmp.Equal(got, want) {
+			t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+		}
+	})
+	t.Run("rollback on error", func(t *testing.T) {
+		db, conn := txDB(t, nil)
+		err := db.WithTx(ctx, nil, func(tx *Tx) error {
+			return errFn
+		})
+		if !errors.Is(err, errFn) {
+			t.Fatalf("got %+v want %+v", err, errFn)
+		}
+		want := []string{"BEGIN", "ROLLBACK"}
+		if got := conn.queries(); !cmp.Equal(got, want) {
+			t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+		}
+	})
+	t.Run("rollback on panic", func(t *testing.T) {
+		db, conn := txDB(t, nil)
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Fatalf("got panic %v want boom", p)
+			}
+			want := []string{"BEGIN", "ROLLBACK"}
+			if got := conn.queries(); !cmp.Equal(got, want) {
+				t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+			}
+		}()
+		_ = db.WithTx(ctx, nil, func(tx *Tx) error {
+			panic("boom")
+		})
+	})
+	t.Run("cancelled context", func(t *testing.T) {
+		db, conn := txDB(t, nil)
+		ctx, cancel := context.WithCancel(ctx)
+		err := db.WithTx(ctx, nil, func(tx *Tx) error {
+			cancel()
+			// wait for database/sql to roll back the transaction
+			for !slices.Contains(conn.queries(), "ROLLBACK") {
+				time.Sleep(time.Millisecond)
+			}
+			return ctx.Err()
+		})
+		if !errors.Is(err, context.Canceled) || strings.Contains(err.Error(), "rollback failed") {
+			t.Fatalf("got %+v want %+v without a rollback failure", err, context.Canceled)
+		}
+		want := []string{"BEGIN", "ROLLBACK"}
+		if got := conn.queries(); !cmp.Equal(got, want) {
+			t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+		}
+	})
+	t.Run("rollback failure", func(t *testing.T) {
+		errRollback := errors.New("rollback broke")
+		db, _ := txDB(t, map[string]error{"ROLLBACK": errRollback})
+		err := db.WithTx(ctx, nil, func(tx *Tx) error {
+			return errFn
+		})
+		if !errors.Is(err, errFn) || !errors.Is(err, errRollback) {
+			t.Fatalf("got %+v want both %+v and %+v", err, errFn, errRollback)
+		}
+	})
+	t.Run("commit failure", func(t *testing.T) {
+		errCommit := errors.New("commit broke")
+		var handled []error
+		db, _ := txDB(t, map[string]error{"COMMIT": errCommit}, WithErrorHandler(func(err error) error {
+			handled = append(handled, err)
+			return err
+		}))
+		err := db.WithTx(ctx, nil, func(tx *Tx) error { return nil })
+		if !errors.Is(err, errCommit) {
+			t.Fatalf("got %+v want %+v", err, errCommit)
+		}
+		if len(handled) != 1 {
+			t.Fatalf("got %d handled errors want 1", len(handled))
+		}
+	})
+	t.Run("begin failure", func(t *testing.T) {
+		errBegin := errors.New("begin broke")
+		db, _ := txDB(t, map[string]error{"BEGIN": errBegin})
+		called := false
+		err := db.WithTx(ctx, nil, func(tx *Tx) error {
+			called = true
+			return nil
+		})
+		if !errors.Is(err, errBegin) || called {
+			t.Fatalf("got (%+v, called=%v) want %+v and fn not called", err, called, errBegin)
+		}
+	})
+	t.Run("Begin", func(t *testing.T) {
+		db, conn := txDB(t, nil)
+		tx, err := db.Begin()
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		if err := tx.Commit(); err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		if want := []string{"BEGIN", "COMMIT"}; !cmp.Equal(conn.queries(), want) {
+			t.Fatalf("(-got +want) %s", cmp.Diff(conn.queries(), want))
+		}
+	})
+}
+
+func TestNestedWithTx(t *testing.T) {
+	ctx := context.Background()
+	errFn := errors.New("fn failed")
+
+	// nest runs an insert at each depth up to maxDepth. The function at
+	// failDepth returns errFn after its nested scopes complete, and parents
+	// swallow the errors of their children.
+	var nest func(tx *Tx, depth, maxDepth, failDepth int) error
+	nest = func(tx *Tx, depth, maxDepth, failDepth int) error {
+		if _, err := Exec(ctx, tx, "INSERT "+strconv.Itoa(depth)); err != nil {
+			return err
+		}
+		if depth < maxDepth {
+			err := tx.WithTx(ctx, func(tx *Tx) error {
+				return nest(tx, depth+1, maxDepth, failDepth)
+			})
+			if err != nil && !errors.Is(err, errFn) {
+				return err
+			}
+		}
+		if depth == failDepth {
+			return errFn
+		}
+		return nil
+	}
+
+	for maxDepth := 1; maxDepth <= 3; maxDepth++ {
+		for failDepth := -1; failDepth <= maxDepth; failDepth++ {
+			t.Run(fmt.Sprintf("depth %d fail at %d", maxDepth, failDepth), func(t *testing.T) {
+				db, conn := txDB(t, nil)
+				err := db.WithTx(ctx, nil, func(tx *Tx) error {
+					return nest(tx, 0, maxDepth, failDepth)
+				})
+				if wantErr := failDepth == 0; (err != nil) != wantErr || (wantErr && !errors.Is(err, errFn)) {
+					t.Fatalf("got %+v want error %v", err, wantErr)
+				}
+
+				want := []string{"BEGIN"}
+				for d := 0; d <= maxDepth; d++ {
+					if d > 0 {
+						want = append(want, "SAVEPOINT sp_"+strconv.Itoa(d))
+					}
+					want = append(want, "INSERT "+strconv.Itoa(d))
+				}
+				for d := maxDepth; d >= 0; d-- {
+					switch {
+					case d == 0 && d == failDepth:
+						want = append(want, "ROLLBACK")
+					case d == 0:
+						want = append(want, "COMMIT")
+					case d == failDepth:
+						want = append(want, "ROLLBACK TO SAVEPOINT sp_"+strconv.Itoa(d))
+					default:
+						want = append(want, "RELEASE SAVEPOINT sp_"+strconv.Itoa(d))
+					}
+				}
+				if got := conn.queries(); !cmp.Equal(got, want) {
+					t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+				}
+			})
+		}
+	}
+
+	t.Run("sibling scopes", func(t *testing.T) {
+		db, conn := txDB(t, nil)
+		err := db.WithTx(ctx, nil, func(tx *Tx) error {
+			_ = tx.WithTx(ctx, func(tx *Tx) error { return errFn })
+			return tx.WithTx(ctx, func(tx *Tx) error { return nil })
+		})
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		want := []string{"BEGIN", "SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1", "SAVEPOINT sp_1", "RELEASE SAVEPOINT sp_1", "COMMIT"}
+		if got := conn.queries(); !cmp.Equal(got, want) {
+			t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+		}
+	})
+	t.Run("panic", func(t *testing.T) {
+		db, conn := txDB(t, nil)
+		defer func() {
+			if p := recover(); p != "boom" {
+				t.Fatalf("got panic %v want boom", p)
+			}
+			want := []string{"BEGIN", "SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1", "ROLLBACK"}
+			if got := conn.queries(); !cmp.Equal(got, want) {
+				t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+			}
+		}()
+		_ = db.WithTx(ctx, nil, func(tx *Tx) error {
+			return tx.WithTx(ctx, func(tx *Tx) error { panic("boom") })
+		})
+	})
+	t.Run("rollback to savepoint failure", func(t *testing.T) {
+		errRollback := errors.New("rollback broke")
+		db, _ := txDB(t, map[string]error{"ROLLBACK TO SAVEPOINT sp_1": errRollback})
+		var nestedErr error
+		_ = db.WithTx(ctx, nil, func(tx *Tx) error {
+			nestedErr = tx.WithTx(ctx, func(tx *Tx) error { return errFn })
+			return nil
+		})
+		if !errors.Is(nestedErr, errFn) || !errors.Is(nestedErr, errRollback) {
+			t.Fatalf("got %+v want both %+v and %+v", nestedErr, errFn, errRollback)
+		}
+	})
+	t.Run("dialects", func(t *testing.T) {
+		tests := map[Dialect][]string{
+			SQLServer: {"BEGIN", "SAVE TRANSACTION sp_1", "ROLLBACK TRANSACTION sp_1", "SAVE TRANSACTION sp_1", "COMMIT"},
+			Oracle:    {"BEGIN", "SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1", "SAVEPOINT sp_1", "COMMIT"},
+			Postgres:  {"BEGIN", "SAVEPOINT sp_1", "ROLLBACK TO SAVEPOINT sp_1", "SAVEPOINT sp_1", "RELEASE SAVEPOINT sp_1", "COMMIT"},
+		}
+		for d, want := range tests {
+			db, conn := txDB(t, nil, WithDialect(d))
+			err := db.WithTx(ctx, nil, func(tx *Tx) error {
+				_ = tx.WithTx(ctx, func(tx *Tx) error { return errFn })
+				return tx.WithTx(ctx, func(tx *Tx) error { return nil })
+			})
+			if err != nil {
+				t.Fatalf("%s: got %+v want nil", d.Name(), err)
+			}
+			if got := conn.queries(); !cmp.Equal(got, want) {
+				t.Fatalf("%s: (-got +want) %s", d.Name(), cmp.Diff(got, want))
+			}
+		}
+	})
+	t.Run("no savepoints", func(t *testing.T) {
+		noSavepoints := &dialect{name: "nosavepoints", placeholder: questionMark, quote: `""`}
+		db, _ := txDB(t, nil, WithDialect(noSavepoints))
+		err := db.WithTx(ctx, nil, func(tx *Tx) error {
+			return tx.WithTx(ctx, func(tx *Tx) error { return nil })
+		})
+		if err == nil || !strings.Contains(err.Error(), "savepoints are not supported") {
+			t.Fatalf("got %+v want unsupported error", err)
+		}
+	})
+}
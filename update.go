@@ -0,0 +1,162 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// ErrNoRowsAffected is returned by Update and Delete when no row matched
+// the primary key of the struct.
+var ErrNoRowsAffected = errors.New("no rows affected")
+
+// Update updates the row of table with the primary key of v, which is made
+// up of the fields tagged with the pk option, e.g. `db:"id,pk"`. Composite
+// keys are supported by tagging multiple fields.
+//
+// All other columns are set as described in Insert, excluding auto and
+// readonly fields. If columns are given, only those columns are set.
+//
+// ErrNoRowsAffected is returned if no row was affected. Note that by default
+// MySQL reports rows that were changed rather than matched, so updating a
+// row with its current values is also reported as ErrNoRowsAffected unless
+// the clientFoundRows DSN parameter is set.
+func Update[T any](ctx context.Context, e Execer, table string, v *T, columns ...string) (sql.Result, error) {
+	db := dbOf(e)
+	if v == nil {
+		return nil, db.handleErr(fmt.Errorf("cannot update nil %T", v))
+	}
+	keys, cols, err := updateColumns(db.mapperOrDefault(), reflect.TypeFor[T](), columns)
+	if err != nil {
+		return nil, db.handleErr(err)
+	}
+
+	d := db.dialectOrDefault()
+	var b strings.Builder
+	b.WriteString("UPDATE ")
+	b.WriteString(d.Quote(table))
+	b.WriteString(" SET ")
+	for i, fi := range cols {
+		if i > 0 {
+			b.WriteString(", ")
+		}
+		b.WriteString(d.Quote(fi.Name))
+		b.WriteString(" = ?")
+	}
+	writeKeyCondition(&b, d, keys)
+
+	rv := reflect.ValueOf(v).Elem()
+	args, err := columnValues(rv, cols, make([]any, 0, len(cols)+len(keys)))
+	if err != nil {
+		return nil, db.handleErr(err)
+	}
+	if args, err = columnValues(rv, keys, args); err != nil {
+		return nil, db.handleErr(err)
+	}
+	return execAffected(ctx, e, b.String(), args)
+}
+
+// Delete deletes the row of table with the primary key of v.
+// See Update for how the key is defined.
+//
+// ErrNoRowsAffected is returned if no row was deleted.
+func Delete[T any](ctx context.Context, e Execer, table string, v *T) (sql.Result, error) {
+	db := dbOf(e)
+	if v == nil {
+		return nil, db.handleErr(fmt.Errorf("cannot delete nil %T", v))
+	}
+	keys, err := keyColumns(db.mapperOrDefault(), reflect.TypeFor[T]())
+	if err != nil {
+		return nil, db.handleErr(err)
+	}
+
+	d := db.dialectOrDefault()
+	var b strings.Builder
+	b.WriteString("DELETE FROM ")
+	b.WriteString(d.Quote(table))
+	writeKeyCondition(&b, d, keys)
+
+	args, err := columnValues(reflect.ValueOf(v).Elem(), keys, nil)
+	if err != nil {
+		return nil, db.handleErr(err)
+	}
+	return execAffected(ctx, e, b.String(), args)
+}
+
+// execAffected executes query and returns ErrNoRowsAffected if no rows
+// were affected.
+func execAffected(ctx context.Context, e Execer, query string, args []any) (sql.Result, error) {
+	db := dbOf(e)
+	res, err := exec(ctx, e, query, args, false)
+	if err != nil {
+		return nil, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return res, db.handleErr(err)
+	}
+	if n == 0 {
+		return res, db.handleErr(ErrNoRowsAffected)
+	}
+	return res, nil
+}
+
+// keyColumns returns the fields of t tagged with the pk option.
+func keyColumns(m *Mapper, t reflect.Type) ([]*FieldInfo, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("type must be a struct, got %s", t)
+	}
+	var keys []*FieldInfo
+	for _, fi := range columnFields(m, t) {
+		if fi.HasOption("pk") {
+			keys = append(keys, fi)
+		}
+	}
+	if len(keys) == 0 {
+		return nil, fmt.Errorf("no primary key in %s: tag the key fields with the pk option", t)
+	}
+	return keys, nil
+}
+
+// updateColumns returns the key fields of t and the fields to update.
+// If columns is not empty, only the fields with those names are updated.
+func updateColumns(m *Mapper, t reflect.Type, columns []string) (keys, cols []*FieldInfo, err error) {
+	if keys, err = keyColumns(m, t); err != nil {
+		return nil, nil, err
+	}
+	for _, fi := range columnFields(m, t) {
+		if fi.HasOption("pk") || fi.HasOption("auto") || fi.HasOption("readonly") {
+			continue
+		}
+		if len(columns) > 0 && !slices.Contains(columns, fi.Name) {
+			continue
+		}
+		cols = append(cols, fi)
+	}
+	// every column in the whitelist must be updatable
+	for _, name := range columns {
+		if !slices.ContainsFunc(cols, func(fi *FieldInfo) bool { return fi.Name == name }) {
+			return nil, nil, fmt.Errorf("column %s is not an updatable field of %s", name, t)
+		}
+	}
+	if len(cols) == 0 {
+		return nil, nil, fmt.Errorf("no updatable columns in %s", t)
+	}
+	return keys, cols, nil
+}
+
+// writeKeyCondition writes a WHERE clause matching all keys to b.
+func writeKeyCondition(b *strings.Builder, d Dialect, keys []*FieldInfo) {
+	b.WriteString(" WHERE ")
+	for i, fi := range keys {
+		if i > 0 {
+			b.WriteString(" AND ")
+		}
+		b.WriteString(d.Quote(fi.Name))
+		b.WriteString(" = ?")
+	}
+}
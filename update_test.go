@@ -0,0 +1,137 @@
+package dbx
+
+import (
+	"context"
+	"database/sql/driver"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type updateMembership struct {
+	UserID    int64  `db:"user_id,pk"`
+	GroupID   int64  `db:"group_id,pk"`
+	Role      string `db:"role"`
+	Note      string `db:"note"`
+	CreatedAt string `db:"created_at,readonly"`
+}
+
+func TestUpdate(t *testing.T) {
+	ctx := context.Background()
+	affected := int64(1)
+	sqlDB, conn := newFakeDB(t, func(string, []driver.NamedValue) (fakeResult, error) {
+		return fakeResult{rowsAffected: affected}, nil
+	})
+	db := NewDB(sqlDB)
+	m := updateMembership{UserID: 1, GroupID: 2, Role: "admin", Note: "note"}
+
+	tests := []struct {
+		name      string
+		db        Execer
+		columns   []string
+		wantQuery string
+		wantArgs  []any
+	}{
+		{
+			name:      "all columns",
+			db:        db,
+			wantQuery: "UPDATE `membership` SET `role` = ?, `note` = ? WHERE `user_id` = ? AND `group_id` = ?",
+			wantArgs:  []any{"admin", "note", int64(1), int64(2)},
+		},
+		{
+			name:      "whitelist",
+			db:        db,
+			columns:   []string{"note"},
+			wantQuery: "UPDATE `membership` SET `note` = ? WHERE `user_id` = ? AND `group_id` = ?",
+			wantArgs:  []any{"note", int64(1), int64(2)},
+		},
+		{
+			name:      "postgres",
+			db:        NewDB(sqlDB, WithDialect(Postgres)),
+			columns:   []string{"role"},
+			wantQuery: `UPDATE "membership" SET "role" = $1 WHERE "user_id" = $2 AND "group_id" = $3`,
+			wantArgs:  []any{"admin", int64(1), int64(2)},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if _, err := Update(ctx, tt.db, "membership", &m, tt.columns...); err != nil {
+				t.Fatalf("got %+v want nil", err)
+			}
+			if got := conn.lastQuery(); got != tt.wantQuery {
+				t.Fatalf("got %q want %q", got, tt.wantQuery)
+			}
+			if got := conn.lastArgs(); !cmp.Equal(got, tt.wantArgs) {
+				t.Fatalf("(-got +want) %s", cmp.Diff(got, tt.wantArgs))
+			}
+		})
+	}
+
+	t.Run("no rows affected", func(t *testing.T) {
+		affected = 0
+		defer func() { affected = 1 }()
+		if _, err := Update(ctx, db, "membership", &m); !errors.Is(err, ErrNoRowsAffected) {
+			t.Fatalf("got %+v want %+v", err, ErrNoRowsAffected)
+		}
+	})
+	t.Run("errors", func(t *testing.T) {
+		type noKey struct {
+			Name string `db:"name"`
+		}
+		type onlyKey struct {
+			ID int64 `db:"id,pk"`
+		}
+		tests := map[string]struct {
+			fn      func() error
+			wantErr string
+		}{
+			"nil":            {func() error { _, err := Update[updateMembership](ctx, db, "t", nil); return err }, "nil"},
+			"no key":         {func() error { _, err := Update(ctx, db, "t", &noKey{}); return err }, "no primary key"},
+			"only key":       {func() error { _, err := Update(ctx, db, "t", &onlyKey{}); return err }, "no updatable columns"},
+			"unknown column": {func() error { _, err := Update(ctx, db, "t", &m, "unknown"); return err }, "column unknown"},
+			"key column":     {func() error { _, err := Update(ctx, db, "t", &m, "user_id"); return err }, "column user_id"},
+			"readonly":       {func() error { _, err := Update(ctx, db, "t", &m, "created_at"); return err }, "column created_at"},
+		}
+		for name, tt := range tests {
+			if err := tt.fn(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: got %+v want error containing %q", name, err, tt.wantErr)
+			}
+		}
+	})
+}
+
+func TestDelete(t *testing.T) {
+	ctx := context.Background()
+	affected := int64(1)
+	sqlDB, conn := newFakeDB(t, func(string, []driver.NamedValue) (fakeResult, error) {
+		return fakeResult{rowsAffected: affected}, nil
+	})
+	m := updateMembership{UserID: 1, GroupID: 2}
+
+	if _, err := Delete(ctx, sqlDB, "membership", &m); err != nil {
+		t.Fatalf("got %+v want nil", err)
+	}
+	want := []string{"DELETE FROM `membership` WHERE `user_id` = ? AND `group_id` = ?"}
+	if got := conn.queries(); !cmp.Equal(got, want) {
+		t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+	}
+	if got, want := conn.lastArgs(), []any{int64(1), int64(2)}; !cmp.Equal(got, want) {
+		t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+	}
+
+	affected = 0
+	if _, err := Delete(ctx, NewDB(sqlDB), "membership", &m); !errors.Is(err, ErrNoRowsAffected) {
+		t.Fatalf("got %+v want %+v", err, ErrNoRowsAffected)
+	}
+	if _, err := Delete[updateMembership](ctx, sqlDB, "membership", nil); err == nil {
+		t.Fatal("got nil want error")
+	}
+	type noKey struct {
+		Name string `db:"name"`
+	}
+	if _, err := Delete(ctx, sqlDB, "t", &noKey{}); err == nil || !strings.Contains(err.Error(), "no primary key") {
+		t.Fatalf("got %+v want no primary key error", err)
+	}
+}
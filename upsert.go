@@ -0,0 +1,261 @@
+package dbx
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"reflect"
+	"slices"
+	"strings"
+)
+
+// UpsertAction reports what Upsert did to the row.
+type UpsertAction int
+
+const (
+	// UpsertUnknown means the database doesn't report whether the row was
+	// inserted or updated.
+	UpsertUnknown UpsertAction = iota
+	// UpsertInserted means a new row was inserted.
+	UpsertInserted
+	// UpsertUpdated means an existing row was updated.
+	UpsertUpdated
+	// UpsertUnchanged means an existing row already had the same values.
+	// Only MySQL reports this.
+	UpsertUnchanged
+)
+
+func (a UpsertAction) String() string {
+	switch a {
+	case UpsertInserted:
+		return "inserted"
+	case UpsertUpdated:
+		return "updated"
+	case UpsertUnchanged:
+		return "unchanged"
+	default:
+		return "unknown"
+	}
+}
+
+// Upsert inserts v into table, or updates the existing row if the insert
+// conflicts with a unique key. Columns are chosen from the fields of T as
+// described in Insert. On conflict, the given updateColumns are set to the
+// inserted values, or all inserted columns except the key columns if none
+// are given.
+//
+// The key columns are the fields tagged with the unique option, e.g.
+// `db:"email,unique"`, or the inserted fields tagged with the pk option if
+// there are none. An auto key is never inserted, so it can't conflict; tag
+// the fields of a unique key of the table instead.
+//
+// The statement depends on the Upsert capability of the Dialect:
+//   - MySQL uses INSERT ... ON DUPLICATE KEY UPDATE col = VALUES(col),
+//     which handles conflicts with any unique key.
+//   - Postgres and SQLite use INSERT ... ON CONFLICT (key) DO UPDATE SET
+//     col = EXCLUDED.col, with the key columns as the conflict target.
+//
+// If the Dialect supports RETURNING and e is also a Queryer, the auto field,
+// if any, is returned by the statement and written back after both inserts
+// and updates, and with the ReturningXmax capability the action is returned
+// as well. Otherwise the auto field is written back after an insert as in
+// Insert, and for MySQL the action is derived from the rows affected. In
+// other cases UpsertUnknown is returned.
+func Upsert[T any](ctx context.Context, e Execer, table string, v *T, updateColumns ...string) (UpsertAction, error) {
+	db := dbOf(e)
+	if v == nil {
+		return UpsertUnknown, db.handleErr(fmt.Errorf("cannot upsert nil %T", v))
+	}
+	t := reflect.TypeFor[T]()
+	m, d := db.mapperOrDefault(), db.dialectOrDefault()
+	cols, auto, err := insertColumns(m, t)
+	if err != nil {
+		return UpsertUnknown, db.handleErr(err)
+	}
+	keys := conflictColumns(cols)
+	updates, err := upsertUpdates(cols, keys, updateColumns, t)
+	if err != nil {
+		return UpsertUnknown, db.handleErr(err)
+	}
+
+	var b strings.Builder
+	b.WriteString(insertQuery(d, table, cols, 1))
+	switch d.Capabilities().Upsert {
+	case OnDuplicateKeyUpdate:
+		b.WriteString(" ON DUPLICATE KEY UPDATE ")
+		for i, fi := range updates {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			col := d.Quote(fi.Name)
+			b.WriteString(col + " = VALUES(" + col + ")")
+		}
+	case OnConflictDoUpdate:
+		if len(keys) == 0 {
+			return UpsertUnknown, db.handleErr(fmt.Errorf("no conflict target in %s: tag the fields of a unique key with the unique option", t))
+		}
+		b.WriteString(" ON CONFLICT (")
+		for i, fi := range keys {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			b.WriteString(d.Quote(fi.Name))
+		}
+		b.WriteString(") DO UPDATE SET ")
+		for i, fi := range updates {
+			if i > 0 {
+				b.WriteString(", ")
+			}
+			col := d.Quote(fi.Name)
+			b.WriteString(col + " = EXCLUDED." + col)
+		}
+	default:
+		return UpsertUnknown, db.handleErr(fmt.Errorf("upsert is not supported by dialect %s", d.Name()))
+	}
+
+	rv := reflect.ValueOf(v).Elem()
+	args, err := columnValues(rv, cols, nil)
+	if err != nil {
+		return UpsertUnknown, db.handleErr(err)
+	}
+
+	caps := d.Capabilities()
+	if q, ok := e.(Queryer); ok && caps.Returning && (caps.ReturningXmax || auto != nil) {
+		var (
+			returning []string
+			dest      []any
+			inserted  bool
+			id        int64
+		)
+		if caps.ReturningXmax {
+			// xmax is 0 for rows inserted by the current transaction
+			returning, dest = append(returning, "(xmax = 0)"), append(dest, &inserted)
+		}
+		if auto != nil {
+			returning, dest = append(returning, d.Quote(auto.Name)), append(dest, &id)
+		}
+		if err := queryRow(ctx, q, b.String()+" RETURNING "+strings.Join(returning, ", "), args, dest...); err != nil {
+			return UpsertUnknown, err
+		}
+		if auto != nil {
+			if err := setInt(fieldByIndexes(rv, auto.Traversal), id); err != nil {
+				return UpsertUnknown, db.handleErr(fmt.Errorf("failed to set %s of %T: %w", auto.Name, v, err))
+			}
+		}
+		switch {
+		case !caps.ReturningXmax:
+			return UpsertUnknown, nil
+		case inserted:
+			return UpsertInserted, nil
+		default:
+			return UpsertUpdated, nil
+		}
+	}
+
+	res, err := exec(ctx, e, b.String(), args, false)
+	if err != nil {
+		return UpsertUnknown, err
+	}
+	if caps.Upsert != OnDuplicateKeyUpdate {
+		return UpsertUnknown, nil
+	}
+	// MySQL reports 1 row affected for an insert, 2 for an update and 0 if
+	// the existing row was not changed
+	n, err := res.RowsAffected()
+	if err != nil {
+		return UpsertUnknown, db.handleErr(err)
+	}
+	switch n {
+	case 0:
+		return UpsertUnchanged, nil
+	case 1:
+		if auto != nil {
+			id, err := res.LastInsertId()
+			if err != nil {
+				return UpsertInserted, db.handleErr(err)
+			}
+			if err := setInt(fieldByIndexes(rv, auto.Traversal), id); err != nil {
+				return UpsertInserted, db.handleErr(fmt.Errorf("failed to set %s of %T: %w", auto.Name, v, err))
+			}
+		}
+		return UpsertInserted, nil
+	default:
+		return UpsertUpdated, nil
+	}
+}
+
+// queryRow runs query, a statement built by this package that returns one
+// row, and scans its columns into dest. The query is reported to the hooks
+// of the DB of q and its errors are wrapped as in Select.
+func queryRow(ctx context.Context, q Queryer, query string, args []any, dest ...any) error {
+	db := dbOf(q)
+	bound, boundArgs, err := db.bind(query, args, false)
+	if err != nil {
+		return db.queryErr(err, query, args, nil, -1)
+	}
+	_, inTx := q.(*Tx)
+	ctx, after := db.beforeQuery(ctx, inTx, QuerySelect, bound, boundArgs)
+	scanned := 0
+	var failed error
+	defer func() { after(scanned, failed) }()
+
+	rows, err := db.queryContext(ctx, q, bound, boundArgs)
+	if err != nil {
+		failed = db.queryErr(err, bound, boundArgs, nil, -1)
+		return failed
+	}
+	defer func() { _ = rows.Close() }()
+	if !rows.Next() {
+		if err = rows.Err(); err == nil {
+			err = sql.ErrNoRows
+		}
+		failed = db.queryErr(err, bound, boundArgs, nil, -1)
+		return failed
+	}
+	if err := rows.Scan(dest...); err != nil {
+		columns, _ := rows.Columns()
+		failed = db.queryErr(fmt.Errorf("failed to scan values: %w", err), bound, boundArgs, columns, 0)
+		return failed
+	}
+	scanned = 1
+	return nil
+}
+
+// conflictColumns returns the key columns of an upsert of cols: the fields
+// tagged with the unique option, or else those tagged with the pk option.
+func conflictColumns(cols []*FieldInfo) []*FieldInfo {
+	for _, opt := range []string{"unique", "pk"} {
+		var keys []*FieldInfo
+		for _, fi := range cols {
+			if fi.HasOption(opt) {
+				keys = append(keys, fi)
+			}
+		}
+		if len(keys) > 0 {
+			return keys
+		}
+	}
+	return nil
+}
+
+// upsertUpdates returns the columns of cols to update on conflict: the
+// columns named in updateColumns, or all columns except primary keys and
+// the key columns.
+func upsertUpdates(cols, keys []*FieldInfo, updateColumns []string, t reflect.Type) ([]*FieldInfo, error) {
+	var updates []*FieldInfo
+	for _, fi := range cols {
+		isKey := fi.HasOption("pk") || slices.Contains(keys, fi)
+		if (len(updateColumns) == 0 && !isKey) || slices.Contains(updateColumns, fi.Name) {
+			updates = append(updates, fi)
+		}
+	}
+	for _, name := range updateColumns {
+		if !slices.ContainsFunc(updates, func(fi *FieldInfo) bool { return fi.Name == name }) {
+			return nil, fmt.Errorf("column %s is not an insertable field of %s", name, t)
+		}
+	}
+	if len(updates) == 0 {
+		return nil, fmt.Errorf("no columns to update on conflict in %s", t)
+	}
+	return updates, nil
+}
@@ -0,0 +1,202 @@
+package dbx
+
+import (
+	"context"
+	"database/sql/driver"
+	"strings"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+type upsertUser struct {
+	ID    int64  `db:"id,pk,auto"`
+	Email string `db:"email,unique"`
+	Name  string `db:"name"`
+	Age   int    `db:"age"`
+}
+
+// upsertDialect is a custom dialect based on Postgres.
+type upsertDialect struct {
+	Dialect
+}
+
+func TestUpsert(t *testing.T) {
+	ctx := context.Background()
+	var (
+		affected int64
+		inserted bool
+	)
+	sqlDB, conn := newFakeDB(t, func(query string, _ []driver.NamedValue) (fakeResult, error) {
+		if _, returning, ok := strings.Cut(query, " RETURNING "); ok {
+			// the action and the key of the row, as requested
+			var res fakeResult
+			if strings.Contains(returning, "xmax") {
+				res.columns, res.rows = append(res.columns, "?column?"), [][]driver.Value{{inserted}}
+			}
+			if strings.HasSuffix(returning, `"id"`) {
+				res.columns = append(res.columns, "id")
+				if res.rows == nil {
+					res.rows = [][]driver.Value{{}}
+				}
+				res.rows[0] = append(res.rows[0], int64(9))
+			}
+			return res, nil
+		}
+		return fakeResult{rowsAffected: affected, lastInsertID: 7}, nil
+	})
+
+	t.Run("MySQL", func(t *testing.T) {
+		db := NewDB(sqlDB)
+		tests := []struct {
+			affected   int64
+			columns    []string
+			wantAction UpsertAction
+			wantQuery  string
+			wantID     int64
+		}{
+			{
+				affected:   1,
+				wantAction: UpsertInserted,
+				wantQuery:  "INSERT INTO `user` (`email`, `name`, `age`) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), `age` = VALUES(`age`)",
+				wantID:     7,
+			},
+			{
+				affected:   2,
+				columns:    []string{"age"},
+				wantAction: UpsertUpdated,
+				wantQuery:  "INSERT INTO `user` (`email`, `name`, `age`) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE `age` = VALUES(`age`)",
+			},
+			{
+				affected:   0,
+				wantAction: UpsertUnchanged,
+				wantQuery:  "INSERT INTO `user` (`email`, `name`, `age`) VALUES (?, ?, ?) ON DUPLICATE KEY UPDATE `name` = VALUES(`name`), `age` = VALUES(`age`)",
+			},
+		}
+		for _, tt := range tests {
+			t.Run(tt.wantAction.String(), func(t *testing.T) {
+				affected = tt.affected
+				u := upsertUser{Email: "a@b.c", Name: "A", Age: 20}
+				action, err := Upsert(ctx, db, "user", &u, tt.columns...)
+				if err != nil {
+					t.Fatalf("got %+v want nil", err)
+				}
+				if action != tt.wantAction {
+					t.Fatalf("got %s want %s", action, tt.wantAction)
+				}
+				if got := conn.lastQuery(); got != tt.wantQuery {
+					t.Fatalf("got %q want %q", got, tt.wantQuery)
+				}
+				if got, want := conn.lastArgs(), []any{"a@b.c", "A", int64(20)}; !cmp.Equal(got, want) {
+					t.Fatalf("(-got +want) %s", cmp.Diff(got, want))
+				}
+				if u.ID != tt.wantID {
+					t.Fatalf("got ID %d want %d", u.ID, tt.wantID)
+				}
+			})
+		}
+	})
+	t.Run("Postgres", func(t *testing.T) {
+		db := NewDB(sqlDB, WithDialect(Postgres))
+		const wantQuery = `INSERT INTO "user" ("email", "name", "age") VALUES ($1, $2, $3) ON CONFLICT ("email") DO UPDATE SET "name" = EXCLUDED."name" RETURNING (xmax = 0), "id"`
+		for _, ins := range []bool{true, false} {
+			inserted = ins
+			u := upsertUser{Email: "a@b.c"}
+			action, err := Upsert(ctx, db, "user", &u, "name")
+			if err != nil {
+				t.Fatalf("got %+v want nil", err)
+			}
+			want := UpsertUpdated
+			if ins {
+				want = UpsertInserted
+			}
+			if action != want {
+				t.Fatalf("got %s want %s", action, want)
+			}
+			if got := conn.lastQuery(); got != wantQuery {
+				t.Fatalf("got %q want %q", got, wantQuery)
+			}
+			// the key is returned for inserted and updated rows
+			if u.ID != 9 {
+				t.Fatalf("got ID %d want 9", u.ID)
+			}
+		}
+	})
+	t.Run("custom Postgres dialect", func(t *testing.T) {
+		db := NewDB(sqlDB, WithDialect(upsertDialect{Postgres}))
+		inserted = true
+		action, err := Upsert(ctx, db, "user", &upsertUser{Email: "a@b.c"})
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		if action != UpsertInserted {
+			t.Fatalf("got %s want %s", action, UpsertInserted)
+		}
+	})
+	t.Run("pk conflict target", func(t *testing.T) {
+		type country struct {
+			Code string `db:"code,pk"`
+			Name string `db:"name"`
+		}
+		db := NewDB(sqlDB, WithDialect(SQLite))
+		if _, err := Upsert(ctx, db, "country", &country{Code: "JP", Name: "Japan"}); err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		const wantQuery = `INSERT INTO "country" ("code", "name") VALUES (?, ?) ON CONFLICT ("code") DO UPDATE SET "name" = EXCLUDED."name"`
+		if got := conn.lastQuery(); got != wantQuery {
+			t.Fatalf("got %q want %q", got, wantQuery)
+		}
+	})
+	t.Run("SQLite", func(t *testing.T) {
+		db := NewDB(sqlDB, WithDialect(SQLite))
+		u := upsertUser{Email: "a@b.c"}
+		action, err := Upsert(ctx, db, "user", &u)
+		if err != nil {
+			t.Fatalf("got %+v want nil", err)
+		}
+		if action != UpsertUnknown {
+			t.Fatalf("got %s want %s", action, UpsertUnknown)
+		}
+		const wantQuery = `INSERT INTO "user" ("email", "name", "age") VALUES (?, ?, ?) ON CONFLICT ("email") DO UPDATE SET "name" = EXCLUDED."name", "age" = EXCLUDED."age" RETURNING "id"`
+		if got := conn.lastQuery(); got != wantQuery {
+			t.Fatalf("got %q want %q", got, wantQuery)
+		}
+		if u.ID != 9 {
+			t.Fatalf("got ID %d want 9", u.ID)
+		}
+	})
+	t.Run("errors", func(t *testing.T) {
+		type noKey struct {
+			Name string `db:"name"`
+		}
+		type onlyKey struct {
+			ID string `db:"id,pk"`
+		}
+		type autoKey struct {
+			ID   int64  `db:"id,pk,auto"`
+			Name string `db:"name"`
+		}
+		tests := map[string]struct {
+			fn      func() (UpsertAction, error)
+			wantErr string
+		}{
+			"nil": {func() (UpsertAction, error) { return Upsert[upsertUser](ctx, sqlDB, "t", nil) }, "nil"},
+			"unsupported dialect": {func() (UpsertAction, error) {
+				return Upsert(ctx, NewDB(sqlDB, WithDialect(SQLServer)), "t", &upsertUser{})
+			}, "not supported by dialect sqlserver"},
+			"no conflict target": {func() (UpsertAction, error) {
+				return Upsert(ctx, NewDB(sqlDB, WithDialect(Postgres)), "t", &noKey{})
+			}, "no conflict target"},
+			"auto key": {func() (UpsertAction, error) {
+				return Upsert(ctx, NewDB(sqlDB, WithDialect(SQLite)), "t", &autoKey{})
+			}, "no conflict target in dbx.autoKey"},
+			"unknown column": {func() (UpsertAction, error) { return Upsert(ctx, sqlDB, "t", &upsertUser{}, "id") }, "column id"},
+			"no updates":     {func() (UpsertAction, error) { return Upsert(ctx, sqlDB, "t", &onlyKey{}) }, "no columns to update"},
+		}
+		for name, tt := range tests {
+			if _, err := tt.fn(); err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("%s: got %+v want error containing %q", name, err, tt.wantErr)
+			}
+		}
+	})
+}